@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"testing"
@@ -36,9 +37,51 @@ func BenchmarkGol(b *testing.B) {
 			for i := 0; i < b.N; i++ {
 				events := make(chan gol.Event)
 				go gol.Run(p, events, nil)
-				//for range events {
-				//
-				//}
+				for range events {
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkGolAllocs runs b.N turns through gol.RunContext once the run has warmed up, so the allocations
+// per op are those of a full turn of the distributor, events included, without loading and saving the board.
+// gol's TestTurnAllocs checks they are no more than the events sent.
+// go test -run ^$ -bench BenchmarkGolAllocs
+func BenchmarkGolAllocs(b *testing.B) {
+	os.Stdout = nil
+	for _, threads := range []int{1, 4, 16} {
+		p := gol.Params{
+			Turns:       1 << 30,
+			Threads:     threads,
+			ImageWidth:  512,
+			ImageHeight: 512,
+			OutputDir:   b.TempDir(),
+		}
+		name := fmt.Sprintf("%dx%d-%d", p.ImageWidth, p.ImageHeight, p.Threads)
+		b.Run(name, func(b *testing.B) {
+			ctx, cancel := context.WithCancel(context.Background())
+			events := make(chan gol.Event, 1000)
+			go gol.RunContext(ctx, p, events, nil)
+			turn := func() {
+				for event := range events {
+					if _, ok := event.(gol.TurnComplete); ok {
+						return
+					}
+				}
+			}
+			for i := 0; i < 100; i++ {
+				turn()
+			}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				turn()
+			}
+			b.StopTimer()
+			cancel()
+			for range events {
 			}
 		})
 	}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// TestBoundary tests the 16x16 image under every boundary using 1-16 worker threads.
+// The torus must match the check images on 0, 1 and 100 turns. The other boundaries must match them
+// while the glider is still far from the edges, and must give the same board whatever the thread count.
+// With a dead border the glider crashes into the bottom edge and settles as a block.
+func TestBoundary(t *testing.T) {
+	boundaries := []gol.Boundary{gol.Torus, gol.DeadBorder, gol.Reflect, gol.KleinBottle, gol.CrossSurface}
+	for _, boundary := range boundaries {
+		for _, turns := range []int{0, 1, 100} {
+			p := gol.Params{ImageWidth: 16, ImageHeight: 16, Turns: turns, Boundary: boundary}
+			var expectedAlive []util.Cell
+			if boundary == gol.DeadBorder && turns == 100 {
+				expectedAlive = []util.Cell{{X: 12, Y: 14}, {X: 13, Y: 14}, {X: 12, Y: 15}, {X: 13, Y: 15}}
+			} else if boundary == gol.Torus || turns < 100 {
+				expectedAlive = readAliveCells(
+					"check/images/"+fmt.Sprintf("%vx%vx%v.pgm", p.ImageWidth, p.ImageHeight, turns),
+					p.ImageWidth,
+					p.ImageHeight,
+				)
+			}
+			for threads := 1; threads <= 16; threads++ {
+				p.Threads = threads
+				testName := fmt.Sprintf("%v/%dx%dx%d-%d", boundary, p.ImageWidth, p.ImageHeight, p.Turns, p.Threads)
+				t.Run(testName, func(t *testing.T) {
+					events := make(chan gol.Event)
+					go gol.Run(p, events, nil)
+					var cells []util.Cell
+					for event := range events {
+						switch e := event.(type) {
+						case gol.FinalTurnComplete:
+							cells = e.Alive
+						}
+					}
+					if expectedAlive == nil {
+						expectedAlive = cells
+					}
+					assertEqualBoard(t, cells, expectedAlive, p)
+				})
+			}
+		}
+	}
+}
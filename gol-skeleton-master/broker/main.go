@@ -0,0 +1,25 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// main starts a broker with 'go run ./broker'. Workers register with it and controllers run on it with -broker.
+func main() {
+	port := flag.String(
+		"port",
+		"8030",
+		"Specify the port to listen on. Defaults to 8030.")
+
+	flag.Parse()
+
+	listener, err := net.Listen("tcp", ":"+*port)
+	util.Check(err)
+	fmt.Println("Broker listening on", listener.Addr())
+	util.Check(gol.ServeBroker(listener))
+}
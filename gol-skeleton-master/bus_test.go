@@ -0,0 +1,107 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// busResult is what a subscriber to a bus saw of a run.
+type busResult struct {
+	alive    []util.Cell
+	final    []util.Cell
+	lastTurn int
+	ordered  bool
+}
+
+// watch builds the board from the flip events of subscription, read with delay between each event.
+func watch(p gol.Params, subscription *gol.Subscription, delay time.Duration) busResult {
+	world := make([][]bool, p.ImageHeight)
+	for y := range world {
+		world[y] = make([]bool, p.ImageWidth)
+	}
+	flip := func(cells []util.Cell) {
+		for _, cell := range cells {
+			world[cell.Y][cell.X] = !world[cell.Y][cell.X]
+		}
+	}
+
+	result := busResult{ordered: true}
+	for event := range subscription.Events() {
+		switch e := event.(type) {
+		case gol.CellFlipped:
+			flip([]util.Cell{e.Cell})
+		case gol.CellsFlipped:
+			flip(e.Cells)
+		case gol.RowsFlipped:
+			flip(e.Cells())
+		case gol.TurnComplete:
+			result.ordered = result.ordered && e.CompletedTurns > result.lastTurn
+			result.lastTurn = e.CompletedTurns
+		case gol.FinalTurnComplete:
+			result.final = e.Alive
+		}
+		time.Sleep(delay)
+	}
+
+	for y, row := range world {
+		for x, cell := range row {
+			if cell {
+				result.alive = append(result.alive, util.Cell{X: x, Y: y})
+			}
+		}
+	}
+	return result
+}
+
+// TestBus tests that subscribers to a bus with different filters and policies can all watch the same run,
+// each building the right board from its events, without a slow or idle subscriber holding the run up.
+func TestBus(t *testing.T) {
+	p := gol.Params{ImageWidth: 64, ImageHeight: 64, Turns: 100, Threads: 4, OutputDir: t.TempDir()}
+	expected := readAliveCells("check/images/64x64x100.pgm", 64, 64)
+
+	var bus gol.Bus
+	subscribers := []struct {
+		name     string
+		sub      *gol.Subscription
+		delay    time.Duration
+		onlyLast bool
+	}{
+		{"window", bus.Subscribe(gol.Filter{}, 10, gol.CoalesceFlips), 100 * time.Microsecond, false},
+		{"every 10", bus.Subscribe(gol.Filter{Every: 10}, 10, gol.Block), 0, false},
+		{"final", bus.Subscribe(gol.Filter{Types: []gol.Event{gol.FinalTurnComplete{}}}, 1, gol.Block), 0, true},
+	}
+	idle := bus.Subscribe(gol.Filter{}, 1, gol.DropOldest)
+	defer idle.Unsubscribe()
+
+	results := make([]chan busResult, len(subscribers))
+	for i, s := range subscribers {
+		results[i] = make(chan busResult, 1)
+		go func(s *gol.Subscription, delay time.Duration, result chan<- busResult) {
+			result <- watch(p, s, delay)
+		}(s.sub, s.delay, results[i])
+	}
+
+	events := make(chan gol.Event)
+	go bus.Forward(events)
+	go gol.Run(p, events, nil)
+
+	for i, s := range subscribers {
+		result := <-results[i]
+		t.Run(s.name, func(t *testing.T) {
+			assertEqualBoard(t, result.final, expected, p)
+			if s.onlyLast {
+				return
+			}
+			if !result.ordered || result.lastTurn != p.Turns {
+				t.Errorf("ERROR: The TurnComplete events were out of order or ended at turn %v, expected %v", result.lastTurn, p.Turns)
+			}
+			assertEqualBoard(t, result.alive, expected, p)
+		})
+	}
+	if idle.Dropped() == 0 {
+		t.Errorf("ERROR: The idle subscriber dropped no events, expected it to drop all but the first")
+	}
+}
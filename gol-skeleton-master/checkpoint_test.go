@@ -0,0 +1,101 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+)
+
+// finalTurn runs p to the end and returns its final event along with the turn of its first event.
+func finalTurn(t *testing.T, p gol.Params, keyPresses chan rune) (int, gol.FinalTurnComplete) {
+	events := make(chan gol.Event, 1000)
+	go gol.Run(p, events, keyPresses)
+	first := -1
+	var final gol.FinalTurnComplete
+	for event := range events {
+		if first == -1 {
+			first = event.GetCompletedTurns()
+		}
+		switch e := event.(type) {
+		case gol.TurnComplete:
+			if e.CompletedTurns <= first {
+				t.Errorf("ERROR: TurnComplete for turn %v in a run resumed at turn %v", e.CompletedTurns, first)
+			}
+		case gol.FinalTurnComplete:
+			final = e
+		}
+	}
+	return first, final
+}
+
+// saveWithS runs the 64x64 image until 's' is pressed, saving a checkpoint in dir, and quits.
+func saveWithS(dir string) {
+	keyPresses := make(chan rune, 10)
+	events := make(chan gol.Event, 1000)
+	go gol.Run(gol.Params{ImageWidth: 64, ImageHeight: 64, Turns: 100000000, Threads: 4, OutputDir: dir}, events, keyPresses)
+	time.AfterFunc(100*time.Millisecond, func() {
+		keyPresses <- 's'
+	})
+	for event := range events {
+		// Quit after the image, so the run goes on a little past the checkpoint.
+		if _, ok := event.(gol.ImageOutputComplete); ok {
+			keyPresses <- 'q'
+		}
+	}
+}
+
+// testResume resumes the run from the checkpoint in dir, to 50 turns past it, on broker if it is set,
+// and checks that the turns carry on from the checkpoint and end on the same board as a run straight through.
+func testResume(t *testing.T, dir, broker string) {
+	checkpoint := filepath.Join(dir, "64x64.ckpt")
+	saved, _ := finalTurn(t, gol.Params{Input: checkpoint, Threads: 4, OutputDir: dir}, nil)
+	if saved <= 0 {
+		t.Fatalf("ERROR: The checkpoint was saved at turn %v", saved)
+	}
+
+	resume := gol.Params{Input: checkpoint, Turns: saved + 50, Threads: 4, OutputDir: dir, Broker: broker}
+	first, resumed := finalTurn(t, resume, nil)
+	if first != saved || resumed.CompletedTurns != saved+50 {
+		t.Fatalf("ERROR: The resumed run went from turn %v to %v, expected %v to %v",
+			first, resumed.CompletedTurns, saved, saved+50)
+	}
+
+	p := gol.Params{ImageWidth: 64, ImageHeight: 64, Turns: saved + 50, Threads: 1, OutputDir: t.TempDir()}
+	_, expected := finalTurn(t, p, nil)
+	assertEqualBoard(t, resumed.Alive, expected.Alive, p)
+}
+
+// TestCheckpoint tests that a run stopped after saving a checkpoint at turn N,
+// with 's' or periodically, resumes locally or on a broker to the same board at turn N+50 as a run that never stopped.
+func TestCheckpoint(t *testing.T) {
+	t.Run("s", func(t *testing.T) {
+		dir := t.TempDir()
+		saveWithS(dir)
+		testResume(t, dir, "")
+	})
+
+	t.Run("periodic", func(t *testing.T) {
+		dir := t.TempDir()
+		keyPresses := make(chan rune, 10)
+		events := make(chan gol.Event, 1000)
+		p := gol.Params{ImageWidth: 64, ImageHeight: 64, Turns: 100000000, Threads: 4, OutputDir: dir,
+			CheckpointInterval: 20 * time.Millisecond}
+		go gol.Run(p, events, keyPresses)
+		time.AfterFunc(200*time.Millisecond, func() {
+			keyPresses <- 'q'
+		})
+		for range events {
+		}
+		testResume(t, dir, "")
+	})
+
+	t.Run("broker", func(t *testing.T) {
+		c := startDistributed(t, 2)
+		defer c.stop()
+		dir := t.TempDir()
+		saveWithS(dir)
+		testResume(t, dir, c.broker)
+	})
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// TestCommands tests that a run can be controlled with typed commands instead of key presses,
+// and that every command is answered, with an error when the run cannot carry it out.
+func TestCommands(t *testing.T) {
+	dir := t.TempDir()
+	glider := filepath.Join(dir, "glider.rle")
+	if err := os.WriteFile(glider, []byte("x = 3, y = 3\nbob$2bo$3o!\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	p := gol.Params{ImageWidth: 64, ImageHeight: 64, Turns: 100000000, Threads: 4, OutputDir: dir}
+	controls := make(chan gol.Command)
+	events := make(chan gol.Event, 1000)
+	errs := make(chan error, 1)
+	go func() { errs <- gol.RunContext(context.Background(), p, events, controls) }()
+
+	var final gol.FinalTurnComplete
+	var turns []int
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for event := range events {
+			switch e := event.(type) {
+			case gol.TurnComplete:
+				turns = append(turns, e.CompletedTurns)
+			case gol.FinalTurnComplete:
+				final = e
+			}
+		}
+	}()
+
+	// send sends command with a reply channel and returns the reply.
+	replies := make(chan error, 1)
+	send := func(command gol.Command) error {
+		controls <- command
+		return <-replies
+	}
+
+	if err := send(gol.Step{Reply: replies}); !errors.Is(err, gol.ErrNotPaused) {
+		t.Errorf("ERROR: Step while running was answered with %v, expected ErrNotPaused", err)
+	}
+	if err := send(gol.SetSpeed{TurnsPerSecond: -1, Reply: replies}); err == nil {
+		t.Errorf("ERROR: A negative speed was accepted")
+	}
+	if err := send(gol.Pause{Reply: replies}); err != nil {
+		t.Fatalf("ERROR: Pause was answered with %v", err)
+	}
+	if err := send(gol.Step{Turns: 3, Reply: replies}); err != nil {
+		t.Errorf("ERROR: Step was answered with %v", err)
+	}
+	if err := send(gol.Rewind{Turns: 2, Reply: replies}); err != nil {
+		t.Errorf("ERROR: Rewind was answered with %v", err)
+	}
+	if err := send(gol.Rewind{Turns: 1000000, Reply: replies}); !errors.Is(err, gol.ErrNotInHistory) {
+		t.Errorf("ERROR: Rewinding past the start was answered with %v, expected ErrNotInHistory", err)
+	}
+	if err := send(gol.SetCell{Cell: util.Cell{X: 0, Y: 0}, Alive: true, Reply: replies}); err != nil {
+		t.Errorf("ERROR: SetCell was answered with %v", err)
+	}
+	if err := send(gol.SetCell{Cell: util.Cell{X: 64, Y: 0}, Alive: true, Reply: replies}); err == nil {
+		t.Errorf("ERROR: A cell off the board was set")
+	}
+	if err := send(gol.LoadPattern{Path: glider, X: 30, Y: 30, Reply: replies}); err != nil {
+		t.Errorf("ERROR: LoadPattern was answered with %v", err)
+	}
+	if err := send(gol.LoadPattern{Path: glider, X: 62, Y: 62, Reply: replies}); err == nil {
+		t.Errorf("ERROR: A pattern was loaded over the edge of the board")
+	}
+	for _, name := range []string{"board.png", "board.rle", "board.ckpt"} {
+		path := filepath.Join(dir, name)
+		if err := send(gol.Save{Path: path, Reply: replies}); err != nil {
+			t.Errorf("ERROR: Saving to %v was answered with %v", name, err)
+		} else if _, err := os.Stat(path); err != nil {
+			t.Errorf("ERROR: Nothing was saved to %v: %v", name, err)
+		}
+	}
+	if err := send(gol.Save{Path: filepath.Join(dir, "board.txt"), Reply: replies}); err == nil {
+		t.Errorf("ERROR: Saving to a .txt file was accepted")
+	}
+	if err := send(gol.Quit{Reply: replies}); err != nil {
+		t.Errorf("ERROR: Quit was answered with %v", err)
+	}
+	<-done
+	if err := <-errs; err != nil {
+		t.Fatalf("ERROR: RunContext returned %v", err)
+	}
+
+	// Stepping 3 turns and going back 2 shows those turns, and the first edit brings the window
+	// back to the last turn before each edit redraws it.
+	if len(turns) < 9 {
+		t.Fatalf("ERROR: Got the turns %v, expected at least 9", turns)
+	}
+	last := turns[len(turns)-1]
+	end := turns[len(turns)-9:]
+	expected := []int{last - 2, last - 1, last, last - 1, last - 2, last - 1, last, last, last}
+	for i := range expected {
+		if end[i] != expected[i] {
+			t.Errorf("ERROR: Got the turns %v at the end, expected %v", end, expected)
+			break
+		}
+	}
+
+	alive := map[util.Cell]bool{}
+	for _, cell := range final.Alive {
+		alive[cell] = true
+	}
+	if !alive[util.Cell{X: 0, Y: 0}] {
+		t.Errorf("ERROR: The cell set alive is dead at the end")
+	}
+	gliderCells := map[util.Cell]bool{{X: 31, Y: 30}: true, {X: 32, Y: 31}: true, {X: 30, Y: 32}: true, {X: 31, Y: 32}: true, {X: 32, Y: 32}: true}
+	for y := 30; y < 33; y++ {
+		for x := 30; x < 33; x++ {
+			cell := util.Cell{X: x, Y: y}
+			if alive[cell] != gliderCells[cell] {
+				t.Errorf("ERROR: The cell %v is not as in the glider loaded at (30, 30)", cell)
+			}
+		}
+	}
+}
+
+// TestQuitReply tests that Quit is answered once the run has ended, with the error from saving the board.
+func TestQuitReply(t *testing.T) {
+	// A file where the output directory should be cannot be written into.
+	dir := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(dir, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	p := gol.Params{ImageWidth: 16, ImageHeight: 16, Turns: 100000000, Threads: 2, OutputDir: dir}
+	controls := make(chan gol.Command)
+	events := make(chan gol.Event, 1000)
+	go gol.RunContext(context.Background(), p, events, controls)
+
+	replies := make(chan error, 1)
+	controls <- gol.Quit{Reply: replies}
+	var outputError *gol.OutputError
+	if err := <-replies; !errors.As(err, &outputError) {
+		t.Errorf("ERROR: Quit was answered with %v, expected an OutputError", err)
+	}
+	for {
+		select {
+		case _, ok := <-events:
+			if !ok {
+				return
+			}
+		default:
+			t.Fatal("ERROR: Quit was answered before the events channel was closed")
+		}
+	}
+}
+
+// TestStepYields tests that a long Step is taken a turn at a time at the capped speed,
+// and that quitting or cancelling the run cuts it short, with Quit answered straight away rather than after the Step.
+func TestStepYields(t *testing.T) {
+	for _, cancelled := range []bool{false, true} {
+		name := "quit"
+		if cancelled {
+			name = "cancel"
+		}
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			p := gol.Params{ImageWidth: 64, ImageHeight: 64, Turns: 100000000, Threads: 4, OutputDir: t.TempDir(), TurnsPerSecond: 100}
+			controls := make(chan gol.Command)
+			events := make(chan gol.Event, 1000)
+			errs := make(chan error, 1)
+			go func() { errs <- gol.RunContext(ctx, p, events, controls) }()
+
+			stepped := make(chan struct{})
+			final := make(chan gol.FinalTurnComplete, 1)
+			go func() {
+				for event := range events {
+					switch e := event.(type) {
+					case gol.TurnComplete:
+						if e.CompletedTurns == 10 {
+							close(stepped)
+						}
+					case gol.FinalTurnComplete:
+						final <- e
+					}
+				}
+			}()
+
+			replies := make(chan error, 1)
+			controls <- gol.Pause{Reply: replies}
+			if err := <-replies; err != nil {
+				t.Fatalf("ERROR: Pause was answered with %v", err)
+			}
+			stepReplies := make(chan error, 1)
+			start := time.Now()
+			controls <- gol.Step{Turns: 1000000, Reply: stepReplies}
+			<-stepped
+			if elapsed := time.Since(start); elapsed < 80*time.Millisecond {
+				t.Errorf("ERROR: 10 steps at 100 turns/sec took %v, expected at least 80ms", elapsed)
+			}
+
+			if cancelled {
+				cancel()
+			} else {
+				controls <- gol.Quit{Reply: replies}
+			}
+			select {
+			case err := <-stepReplies:
+				if !errors.Is(err, gol.ErrInterrupted) {
+					t.Errorf("ERROR: The Step cut short was answered with %v, expected ErrInterrupted", err)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("ERROR: The Step was still going 5s after the run was ended")
+			}
+			if !cancelled {
+				if err := <-replies; err != nil {
+					t.Errorf("ERROR: Quit was answered with %v", err)
+				}
+			}
+			if e := <-final; e.CompletedTurns >= 1000 {
+				t.Errorf("ERROR: The run ended after %v turns, expected the Step to be cut short", e.CompletedTurns)
+			}
+			<-errs
+		})
+	}
+}
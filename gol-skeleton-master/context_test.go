@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"testing"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+)
+
+// assertNoLeaks waits for the goroutines a run started to stop, failing with their stacks
+// if more than before are still running after a second.
+func assertNoLeaks(t *testing.T, before int) {
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before {
+		if time.Now().After(deadline) {
+			var stacks bytes.Buffer
+			_ = pprof.Lookup("goroutine").WriteTo(&stacks, 1)
+			t.Fatalf("ERROR: %v goroutines are running after the run, expected %v:\n%v",
+				runtime.NumGoroutine(), before, stacks.String())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// TestRunContext tests that cancelling the context of a run quits it as 'q' does, and that
+// no goroutine is left running once a run has been cancelled, has finished or has failed.
+func TestRunContext(t *testing.T) {
+	t.Run("cancel", func(t *testing.T) {
+		before := runtime.NumGoroutine()
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		p := gol.Params{ImageWidth: 64, ImageHeight: 64, Turns: 100000000, Threads: 4, OutputDir: t.TempDir()}
+		events := make(chan gol.Event, 1000)
+		errs := make(chan error, 1)
+		go func() { errs <- gol.RunContext(ctx, p, events, make(chan gol.Command)) }()
+
+		var quit bool
+		var final *gol.FinalTurnComplete
+		for event := range events {
+			switch e := event.(type) {
+			case gol.TurnComplete:
+				if e.CompletedTurns == 10 {
+					cancel()
+				}
+			case gol.StateChange:
+				quit = quit || e.NewState == gol.Quitting
+			case gol.FinalTurnComplete:
+				final = &e
+			}
+		}
+		if !quit || final == nil || final.CompletedTurns < 10 {
+			t.Errorf("ERROR: Cancelling the run did not quit it after turn 10, with Quitting and FinalTurnComplete events")
+		}
+		if err := <-errs; !errors.Is(err, context.Canceled) {
+			t.Errorf("ERROR: RunContext returned %v, expected context.Canceled", err)
+		}
+		assertNoLeaks(t, before)
+	})
+
+	t.Run("cancel while paused", func(t *testing.T) {
+		before := runtime.NumGoroutine()
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		p := gol.Params{ImageWidth: 64, ImageHeight: 64, Turns: 100000000, Threads: 4, OutputDir: t.TempDir()}
+		controls := make(chan gol.Command, 1)
+		controls <- gol.Pause{}
+		events := make(chan gol.Event, 1000)
+		errs := make(chan error, 1)
+		go func() { errs <- gol.RunContext(ctx, p, events, controls) }()
+		for event := range events {
+			if e, ok := event.(gol.StateChange); ok && e.NewState == gol.Paused {
+				cancel()
+			}
+		}
+		if err := <-errs; !errors.Is(err, context.Canceled) {
+			t.Errorf("ERROR: RunContext returned %v, expected context.Canceled", err)
+		}
+		assertNoLeaks(t, before)
+	})
+
+	t.Run("finish", func(t *testing.T) {
+		before := runtime.NumGoroutine()
+		p := gol.Params{ImageWidth: 16, ImageHeight: 16, Turns: 10, Threads: 4, OutputDir: t.TempDir()}
+		events := make(chan gol.Event, 1000)
+		errs := make(chan error, 1)
+		// Without key presses nothing but the end of the turns stops the key handler.
+		go func() { errs <- gol.RunContext(context.Background(), p, events, nil) }()
+		for range events {
+		}
+		if err := <-errs; err != nil {
+			t.Errorf("ERROR: RunContext returned %v for a run that finished", err)
+		}
+		assertNoLeaks(t, before)
+	})
+
+	t.Run("cancel with a hung broker", func(t *testing.T) {
+		// The broker takes connections but never answers a call.
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		conns := make(chan net.Conn, 10)
+		go func() {
+			for {
+				conn, err := listener.Accept()
+				if err != nil {
+					close(conns)
+					return
+				}
+				conns <- conn
+			}
+		}()
+		defer func() {
+			listener.Close()
+			for conn := range conns {
+				conn.Close()
+			}
+		}()
+
+		before := runtime.NumGoroutine()
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		defer cancel()
+		p := gol.Params{ImageWidth: 16, ImageHeight: 16, Turns: 10, Threads: 4, OutputDir: t.TempDir(), Broker: listener.Addr().String()}
+		events := make(chan gol.Event, 1000)
+		errs := make(chan error, 1)
+		go func() { errs <- gol.RunContext(ctx, p, events, nil) }()
+		for range events {
+		}
+		select {
+		case err := <-errs:
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("ERROR: RunContext returned %v, expected context.DeadlineExceeded", err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("ERROR: RunContext was still waiting on the broker after its context was done")
+		}
+		assertNoLeaks(t, before)
+	})
+
+	t.Run("fail", func(t *testing.T) {
+		before := runtime.NumGoroutine()
+		path := filepath.Join(t.TempDir(), "short.pgm")
+		if err := os.WriteFile(path, []byte("P5\n16 16\n255\n\xff\x00"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		p := gol.Params{Input: path, ImageWidth: 16, ImageHeight: 16, Turns: 10, Threads: 4}
+		events := make(chan gol.Event, 1000)
+		errs := make(chan error, 1)
+		go func() { errs <- gol.RunContext(context.Background(), p, events, nil) }()
+		for range events {
+		}
+		if err := <-errs; err == nil {
+			t.Errorf("ERROR: RunContext returned no error for a short image")
+		}
+		assertNoLeaks(t, before)
+	})
+}
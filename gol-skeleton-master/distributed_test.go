@@ -0,0 +1,376 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// cluster is a broker and its workers running on 127.0.0.1.
+type cluster struct {
+	broker    string
+	listeners []net.Listener
+	workers   []*killableListener
+	// served is done once the broker and every worker have stopped serving.
+	served sync.WaitGroup
+}
+
+// killableListener is a listener that can drop every connection it has accepted,
+// so that a worker can die as if its process had been killed.
+type killableListener struct {
+	net.Listener
+	mu    sync.Mutex
+	conns []net.Conn
+}
+
+func (l *killableListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err == nil {
+		l.mu.Lock()
+		l.conns = append(l.conns, conn)
+		l.mu.Unlock()
+	}
+	return conn, err
+}
+
+func (l *killableListener) kill() {
+	l.Close()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, conn := range l.conns {
+		conn.Close()
+	}
+}
+
+// startDistributed starts a broker and the given number of workers on 127.0.0.1.
+func startDistributed(t *testing.T, workers int) *cluster {
+	c := new(cluster)
+	brokerListener, err := net.Listen("tcp", "127.0.0.1:0")
+	util.Check(err)
+	c.serve(brokerListener, gol.ServeBroker)
+	c.broker = brokerListener.Addr().String()
+
+	for i := 0; i < workers; i++ {
+		c.addWorker(t)
+	}
+	return c
+}
+
+func (c *cluster) serve(listener net.Listener, server func(net.Listener) error) {
+	c.listeners = append(c.listeners, listener)
+	c.served.Add(1)
+	go func() {
+		server(listener)
+		c.served.Done()
+	}()
+}
+
+// addWorker starts a worker and registers it with the cluster's broker.
+func (c *cluster) addWorker(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	util.Check(err)
+	worker := &killableListener{Listener: listener}
+	c.workers = append(c.workers, worker)
+	c.serve(worker, gol.ServeWorker)
+	err = gol.RegisterWorker(c.broker, worker.Addr().String())
+	if err != nil {
+		t.Fatalf("ERROR: Worker failed to register: %v", err)
+	}
+}
+
+// stop closes every listener in the cluster.
+func (c *cluster) stop() {
+	for _, listener := range c.listeners {
+		listener.Close()
+	}
+}
+
+// TestDistributed tests 16x16 and 64x64 images on 0, 1 and 100 turns using a broker with 1-4 workers on localhost.
+func TestDistributed(t *testing.T) {
+	for workers := 1; workers <= 4; workers++ {
+		c := startDistributed(t, workers)
+		tests := []gol.Params{
+			{ImageWidth: 16, ImageHeight: 16},
+			{ImageWidth: 64, ImageHeight: 64},
+		}
+		for _, p := range tests {
+			p.Broker = c.broker
+			for _, turns := range []int{0, 1, 100} {
+				p.Turns = turns
+				expectedAlive := readAliveCells(
+					"check/images/"+fmt.Sprintf("%vx%vx%v.pgm", p.ImageWidth, p.ImageHeight, turns),
+					p.ImageWidth,
+					p.ImageHeight,
+				)
+				testName := fmt.Sprintf("%dx%dx%d-%d_workers", p.ImageWidth, p.ImageHeight, p.Turns, workers)
+				t.Run(testName, func(t *testing.T) {
+					events := make(chan gol.Event)
+					go gol.Run(p, events, nil)
+					var cells []util.Cell
+					turnsCompleted := 0
+					for event := range events {
+						switch e := event.(type) {
+						case gol.TurnComplete:
+							turnsCompleted++
+						case gol.FinalTurnComplete:
+							cells = e.Alive
+						}
+					}
+					assert(t, turnsCompleted == p.Turns, "Expected %v TurnComplete events, got %v", p.Turns, turnsCompleted)
+					assertEqualBoard(t, cells, expectedAlive, p)
+				})
+			}
+		}
+		c.stop()
+	}
+}
+
+// finalAlive runs p to completion and returns the alive cells reported by FinalTurnComplete.
+func finalAlive(p gol.Params) []util.Cell {
+	events := make(chan gol.Event)
+	go gol.Run(p, events, nil)
+	var cells []util.Cell
+	for event := range events {
+		switch e := event.(type) {
+		case gol.FinalTurnComplete:
+			cells = e.Alive
+		}
+	}
+	return cells
+}
+
+// TestHaloExchange tests halo-exchange runs using a broker with 1-4 workers on localhost.
+// The torus must match the check images on 0, 1 and 100 turns, and the dead, reflecting
+// and Klein bottle boundaries must match a local run of the same board.
+func TestHaloExchange(t *testing.T) {
+	boundaries := []gol.Boundary{gol.Torus, gol.DeadBorder, gol.Reflect, gol.KleinBottle}
+	for workers := 1; workers <= 4; workers++ {
+		c := startDistributed(t, workers)
+		for _, boundary := range boundaries {
+			for _, size := range []int{16, 64} {
+				for _, turns := range []int{0, 1, 100} {
+					p := gol.Params{ImageWidth: size, ImageHeight: size, Turns: turns, Boundary: boundary, Threads: 1}
+					var expectedAlive []util.Cell
+					if boundary == gol.Torus {
+						expectedAlive = readAliveCells(
+							"check/images/"+fmt.Sprintf("%vx%vx%v.pgm", p.ImageWidth, p.ImageHeight, turns),
+							p.ImageWidth,
+							p.ImageHeight,
+						)
+					} else {
+						expectedAlive = finalAlive(p)
+					}
+					p.Broker = c.broker
+					p.HaloExchange = true
+					testName := fmt.Sprintf("%v/%dx%dx%d-%d_workers", boundary, p.ImageWidth, p.ImageHeight, p.Turns, workers)
+					t.Run(testName, func(t *testing.T) {
+						assertEqualBoard(t, finalAlive(p), expectedAlive, p)
+					})
+				}
+			}
+		}
+		c.stop()
+	}
+}
+
+// TestShutdown tests that pressing 'k' during a distributed run saves the board, quits,
+// and stops the broker and its workers, with and without halo exchange.
+func TestShutdown(t *testing.T) {
+	for _, halo := range []bool{false, true} {
+		t.Run(fmt.Sprintf("halo=%v", halo), func(t *testing.T) {
+			c := startDistributed(t, 3)
+			defer c.stop()
+			params := gol.Params{
+				Turns:        100000000,
+				Threads:      1,
+				ImageWidth:   512,
+				ImageHeight:  512,
+				Broker:       c.broker,
+				HaloExchange: halo,
+			}
+
+			keyPresses := make(chan rune, 10)
+			events := make(chan gol.Event, 1000)
+			golDone := make(chan bool, 1)
+			go func() {
+				gol.Run(params, events, keyPresses)
+				golDone <- true
+			}()
+
+			tester := MakeTester(t, params, keyPresses, events, golDone)
+			go func() {
+				tester.TestStartsExecuting()
+				time.Sleep(500 * time.Millisecond)
+
+				keyPresses <- 'k'
+				tester.TestOutput()
+				tester.TestQuits()
+				tester.Stop(true)
+			}()
+			tester.Loop()
+
+			timeout(t, 5*time.Second, c.served.Wait, "The broker and workers did not stop serving after 'k' was pressed")
+		})
+	}
+}
+
+// TestDetach tests that pressing 'q' during a distributed run leaves the broker computing,
+// and that the next controller picks the run up where it has got to and finishes it.
+// The 64x64 board must match the check image after 100 turns, with and without halo exchange.
+func TestDetach(t *testing.T) {
+	for _, halo := range []bool{false, true} {
+		t.Run(fmt.Sprintf("halo=%v", halo), func(t *testing.T) {
+			c := startDistributed(t, 2)
+			defer c.stop()
+			p := gol.Params{
+				Turns:        100,
+				Threads:      1,
+				ImageWidth:   64,
+				ImageHeight:  64,
+				Broker:       c.broker,
+				HaloExchange: halo,
+			}
+
+			keyPresses := make(chan rune, 10)
+			events := make(chan gol.Event)
+			go gol.Run(p, events, keyPresses)
+			detachedTurn := -1
+			for event := range events {
+				switch e := event.(type) {
+				case gol.TurnComplete:
+					if e.CompletedTurns == 1 {
+						keyPresses <- 'q'
+					}
+				case gol.FinalTurnComplete:
+					detachedTurn = e.CompletedTurns
+				}
+			}
+			assert(t, detachedTurn >= 1, "Expected the first controller to detach after turn 1, got turn %v", detachedTurn)
+
+			// The second controller's parameters must not matter beyond the image size.
+			p.Turns = 1
+			events = make(chan gol.Event)
+			go gol.Run(p, events, nil)
+			attachedTurn := -1
+			turnsCompleted := 0
+			var cells []util.Cell
+			for event := range events {
+				switch e := event.(type) {
+				case gol.StateChange:
+					if e.NewState == gol.Executing && attachedTurn < 0 {
+						attachedTurn = e.CompletedTurns
+					}
+				case gol.TurnComplete:
+					turnsCompleted++
+				case gol.FinalTurnComplete:
+					assert(t, e.CompletedTurns == 100, "Expected the run to finish at turn 100, got %v", e.CompletedTurns)
+					cells = e.Alive
+				}
+			}
+			assert(t, attachedTurn >= detachedTurn, "Expected to attach at turn %v or later, got %v", detachedTurn, attachedTurn)
+			assert(t, turnsCompleted == 100-attachedTurn, "Expected %v TurnComplete events, got %v", 100-attachedTurn, turnsCompleted)
+			p.Turns = 100
+			assertEqualBoard(t, cells, readAliveCells("check/images/64x64x100.pgm", 64, 64), p)
+		})
+	}
+}
+
+// TestWorkerLost tests that a run on 512x512 for 100 turns still matches the check image when a worker
+// is killed after the first turn, with 1-3 workers and with and without halo exchange.
+// With a single worker the broker has to finish the run by itself.
+func TestWorkerLost(t *testing.T) {
+	for _, halo := range []bool{false, true} {
+		for workers := 1; workers <= 3; workers++ {
+			t.Run(fmt.Sprintf("halo=%v/%d_workers", halo, workers), func(t *testing.T) {
+				c := startDistributed(t, workers)
+				defer c.stop()
+				p := gol.Params{
+					Turns:        100,
+					Threads:      1,
+					ImageWidth:   512,
+					ImageHeight:  512,
+					Broker:       c.broker,
+					HaloExchange: halo,
+				}
+
+				events := make(chan gol.Event)
+				go gol.Run(p, events, nil)
+				var lost []string
+				var cells []util.Cell
+				for event := range events {
+					switch e := event.(type) {
+					case gol.TurnComplete:
+						if e.CompletedTurns == 1 {
+							c.workers[0].kill()
+						}
+					case gol.WorkerLost:
+						lost = append(lost, e.Address)
+					case gol.FinalTurnComplete:
+						cells = e.Alive
+					}
+				}
+				assert(t, len(lost) == 1 && lost[0] == c.workers[0].Addr().String(),
+					"Expected a WorkerLost event for %v, got %v", c.workers[0].Addr(), lost)
+				assertEqualBoard(t, cells, readAliveCells("check/images/512x512x100.pgm", 512, 512), p)
+			})
+		}
+	}
+}
+
+// TestElastic tests that a worker can join a running broker and another can leave it,
+// with and without halo exchange. The rows must be shared out again to cover the new set
+// of workers, and the 512x512 board after 500 turns must match a local run.
+func TestElastic(t *testing.T) {
+	for _, halo := range []bool{false, true} {
+		t.Run(fmt.Sprintf("halo=%v", halo), func(t *testing.T) {
+			c := startDistributed(t, 2)
+			defer c.stop()
+			p := gol.Params{Turns: 500, Threads: 1, ImageWidth: 512, ImageHeight: 512, HaloExchange: halo}
+			expectedAlive := finalAlive(p)
+			p.Broker = c.broker
+
+			events := make(chan gol.Event)
+			go gol.Run(p, events, nil)
+			var joined []string
+			var last gol.MembershipChanged
+			var cells []util.Cell
+			for event := range events {
+				switch e := event.(type) {
+				case gol.TurnComplete:
+					switch e.CompletedTurns {
+					case 1:
+						c.addWorker(t)
+					case 2:
+						err := gol.DeregisterWorker(c.broker, c.workers[0].Addr().String())
+						assert(t, err == nil, "Expected the worker to leave, got %v", err)
+					}
+				case gol.WorkerJoined:
+					joined = append(joined, e.Address)
+				case gol.MembershipChanged:
+					last = e
+				case gol.FinalTurnComplete:
+					cells = e.Alive
+				}
+			}
+
+			leaving, joining := c.workers[0].Addr().String(), c.workers[2].Addr().String()
+			assert(t, len(joined) == 1 && joined[0] == joining, "Expected a WorkerJoined event for %v, got %v", joining, joined)
+			owners := make(map[string]bool)
+			startY := 0
+			for _, s := range last.Strips {
+				assert(t, s.StartY == startY && s.EndY > s.StartY, "Expected the strips %v to partition the rows", last.Strips)
+				owners[s.Address] = true
+				startY = s.EndY
+			}
+			assert(t, startY == p.ImageHeight, "Expected the strips %v to cover every row", last.Strips)
+			assert(t, len(owners) == 2 && owners[joining] && !owners[leaving],
+				"Expected the rows to be shared between %v and %v, got %v", c.workers[1].Addr(), joining, last.Strips)
+			assertEqualBoard(t, cells, expectedAlive, p)
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+)
+
+// failedRun runs p, expecting it to stop with a RunError as its last event.
+// It returns the error from the event after checking that Run returned the same one.
+func failedRun(t *testing.T, p gol.Params) error {
+	events := make(chan gol.Event, 1000)
+	errs := make(chan error, 1)
+	go func() { errs <- gol.Run(p, events, make(chan rune, 10)) }()
+	var last gol.Event
+	for event := range events {
+		if _, ok := event.(gol.FinalTurnComplete); ok {
+			t.Errorf("ERROR: A failed run sent %v", event)
+		}
+		last = event
+	}
+	runError, ok := last.(gol.RunError)
+	if !ok {
+		t.Fatalf("ERROR: The last event was %v, expected a RunError", last)
+	}
+	if err := <-errs; err != runError.Err {
+		t.Errorf("ERROR: Run returned %v, expected the error in the RunError %v", err, runError.Err)
+	}
+	return runError.Err
+}
+
+// TestRunError tests that a run which cannot read its input, write its output or reach its broker
+// stops with the error in a RunError event and closes the events channel instead of panicking.
+func TestRunError(t *testing.T) {
+	t.Run("missing input", func(t *testing.T) {
+		p := gol.Params{Input: filepath.Join(t.TempDir(), "missing.pgm"), ImageWidth: 16, ImageHeight: 16, Turns: 1, Threads: 1}
+		if err := failedRun(t, p); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("ERROR: Got the error %v, expected one wrapping fs.ErrNotExist", err)
+		}
+	})
+
+	t.Run("bad pixels", func(t *testing.T) {
+		// The header is fine, so the image is only found to be short once the run has started.
+		path := filepath.Join(t.TempDir(), "short.pgm")
+		if err := os.WriteFile(path, []byte("P5\n16 16\n255\n\xff\x00"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		p := gol.Params{Input: path, ImageWidth: 16, ImageHeight: 16, Turns: 1, Threads: 1}
+		var pgmError *gol.PgmError
+		if err := failedRun(t, p); !errors.As(err, &pgmError) || !errors.Is(err, gol.ErrPgmPixels) {
+			t.Errorf("ERROR: Got the error %v, expected a PgmError wrapping ErrPgmPixels", err)
+		}
+	})
+
+	t.Run("unwritable output", func(t *testing.T) {
+		// A file where the output directory should be cannot be written into.
+		dir := filepath.Join(t.TempDir(), "file")
+		if err := os.WriteFile(dir, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		p := gol.Params{ImageWidth: 16, ImageHeight: 16, Turns: 10, Threads: 2, OutputDir: dir}
+		var outputError *gol.OutputError
+		if err := failedRun(t, p); !errors.As(err, &outputError) {
+			t.Errorf("ERROR: Got the error %v, expected an OutputError", err)
+		}
+	})
+
+	t.Run("unreachable broker", func(t *testing.T) {
+		p := gol.Params{ImageWidth: 16, ImageHeight: 16, Turns: 10, Threads: 2, OutputDir: t.TempDir(), Broker: "127.0.0.1:1"}
+		var brokerError *gol.BrokerError
+		if err := failedRun(t, p); !errors.As(err, &brokerError) || brokerError.Address != p.Broker {
+			t.Errorf("ERROR: Got the error %v, expected a BrokerError for %v", err, p.Broker)
+		}
+	})
+}
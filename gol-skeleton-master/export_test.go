@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/gif"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// imageCells returns the cells drawn alive in img, where each cell is a scale by scale square.
+func imageCells(img image.Image, scale int) []util.Cell {
+	var cells []util.Cell
+	bounds := img.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y += scale {
+		for x := bounds.Min.X; x < bounds.Max.X; x += scale {
+			if r, _, _, _ := img.At(x, y).RGBA(); r != 0 {
+				cells = append(cells, util.Cell{X: x / scale, Y: y / scale})
+			}
+		}
+	}
+	return cells
+}
+
+// TestExport tests that the 16x16 image run for 100 turns saves a PNG image of the final board
+// and records a GIF whose frames match the check images on turns 0, 1 and 100.
+func TestExport(t *testing.T) {
+	dir := t.TempDir()
+	p := gol.Params{ImageWidth: 16, ImageHeight: 16, Turns: 100, Threads: 4, OutputDir: dir, PNG: true, Scale: 3}
+	recorder := &gol.GIFRecorder{Path: filepath.Join(dir, "run.gif"), Every: 1}
+	events := make(chan gol.Event)
+	recorded := make(chan gol.Event)
+	errs := make(chan error, 1)
+	go gol.Run(p, events, nil)
+	go func() { errs <- recorder.Record(p, events, recorded) }()
+	for range recorded {
+	}
+	if err := <-errs; err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := os.Open(filepath.Join(dir, "16x16x100.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	img, err := png.Decode(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Bounds().Dx() != 48 || img.Bounds().Dy() != 48 {
+		t.Errorf("ERROR: The PNG image is %v, expected 48x48", img.Bounds().Size())
+	}
+	assertEqualBoard(t, imageCells(img, 3), readAliveCells("check/images/16x16x100.pgm", 16, 16), p)
+
+	file, err = os.Open(recorder.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	animation, err := gif.DecodeAll(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(animation.Image) != 101 {
+		t.Fatalf("ERROR: The GIF has %v frames, expected 101", len(animation.Image))
+	}
+	for _, turn := range []int{0, 1, 100} {
+		expected := readAliveCells(fmt.Sprintf("check/images/16x16x%v.pgm", turn), 16, 16)
+		p.Turns = turn
+		assertEqualBoard(t, imageCells(animation.Image[turn], 3), expected, p)
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// TestFlips tests that the cells flipped in each turn are sent in at most one event per worker strip,
+// as CellsFlipped events or as RowsFlipped events with FlipRuns set, all before the turn's TurnComplete.
+func TestFlips(t *testing.T) {
+	for _, runs := range []bool{false, true} {
+		p := gol.Params{ImageWidth: 64, ImageHeight: 64, Turns: 100, Threads: 4, OutputDir: t.TempDir(), FlipRuns: runs}
+		t.Run(fmt.Sprintf("runs=%v", runs), func(t *testing.T) {
+			events := make(chan gol.Event, 1000)
+			go gol.Run(p, events, nil)
+
+			world := make([][]bool, p.ImageHeight)
+			for y := range world {
+				world[y] = make([]bool, p.ImageWidth)
+			}
+			// The alive cells of the initial board are sent in strips too, before those of the first turn.
+			turn, flips, limit := 0, 0, 2*p.Threads
+			flip := func(completedTurns int, cells []util.Cell) {
+				if completedTurns != turn {
+					t.Errorf("ERROR: Cells flipped in turn %v were sent after %v turns", completedTurns+1, turn)
+				}
+				if flips++; flips > limit {
+					t.Errorf("ERROR: Got %v flip events in turn %v, expected at most one per strip", flips, turn+1)
+				}
+				for _, cell := range cells {
+					world[cell.Y][cell.X] = !world[cell.Y][cell.X]
+				}
+			}
+
+			var final []util.Cell
+			for event := range events {
+				switch e := event.(type) {
+				case gol.CellFlipped:
+					t.Errorf("ERROR: Got a CellFlipped event, expected the cells to be batched")
+				case gol.CellsFlipped:
+					if runs {
+						t.Errorf("ERROR: Got a CellsFlipped event, expected RowsFlipped")
+					}
+					flip(e.CompletedTurns, e.Cells)
+				case gol.RowsFlipped:
+					if !runs {
+						t.Errorf("ERROR: Got a RowsFlipped event, expected CellsFlipped")
+					}
+					flip(e.CompletedTurns, e.Cells())
+				case gol.TurnComplete:
+					turn, flips, limit = e.CompletedTurns, 0, p.Threads
+				case gol.FinalTurnComplete:
+					final = e.Alive
+				}
+			}
+
+			var alive []util.Cell
+			for y, row := range world {
+				for x, cell := range row {
+					if cell {
+						alive = append(alive, util.Cell{X: x, Y: y})
+					}
+				}
+			}
+			assertEqualBoard(t, alive, final, p)
+			assertEqualBoard(t, alive, readAliveCells("check/images/64x64x100.pgm", 64, 64), p)
+		})
+	}
+}
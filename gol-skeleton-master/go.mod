@@ -1,5 +1,5 @@
 module uk.ac.bris.cs/gameoflife
 
-go 1.17
+go 1.18
 
 require github.com/veandco/go-sdl2 v0.4.38
@@ -0,0 +1,242 @@
+package gol
+
+import (
+	"math/bits"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// board is the bit-packed world used by the compute kernel, 64 cells per uint64 word.
+//
+// Every row holds width+2 bits. Bit 0 and bit width+1 are ghost cells standing for whatever
+// lies beyond the left and right edges, and there is a ghost row above the first row and
+// below the last one. Once fillGhosts has applied the boundary to the ghosts the kernel can
+// treat every cell as an interior cell.
+// Cell (x, y) lives in bit (x+1)%64 of word (x+1)/64 of row y+1.
+type board struct {
+	width, height int
+	boundary      Boundary
+	stride        int      // words per row
+	mask          []uint64 // bits of a row that hold real (non-ghost) cells
+	words         []uint64
+}
+
+func newBoard(width, height int, boundary Boundary) *board {
+	return boardFromWords(width, height, boundary, make([]uint64, (height+2)*rowWords(width)))
+}
+
+// boardFromWords wraps words, which must hold height+2 packed rows ghost rows included, in a board.
+func boardFromWords(width, height int, boundary Boundary, words []uint64) *board {
+	stride := rowWords(width)
+	mask := make([]uint64, stride)
+	for x := 1; x <= width; x++ {
+		mask[x>>6] |= 1 << (x & 63)
+	}
+	return &board{
+		width:    width,
+		height:   height,
+		boundary: boundary,
+		stride:   stride,
+		mask:     mask,
+		words:    words,
+	}
+}
+
+// rowWords is the number of words in a packed row of width cells plus its two ghost cells.
+func rowWords(width int) int {
+	return (width + 2 + 63) / 64
+}
+
+// row returns the words of row y, where y may be -1 or height for the ghost rows.
+func (b *board) row(y int) []uint64 {
+	start := (y + 1) * b.stride
+	return b.words[start : start+b.stride]
+}
+
+func (b *board) get(x, y int) bool {
+	bit := x + 1
+	return b.row(y)[bit>>6]>>(bit&63)&1 == 1
+}
+
+func (b *board) set(x, y int, alive bool) {
+	bit := x + 1
+	if alive {
+		b.row(y)[bit>>6] |= 1 << (bit & 63)
+	} else {
+		b.row(y)[bit>>6] &^= 1 << (bit & 63)
+	}
+}
+
+// clone returns a copy of the board that does not share its words.
+func (b *board) clone() *board {
+	return boardFromWords(b.width, b.height, b.boundary, append([]uint64(nil), b.words...))
+}
+
+// flip flips every cell in cells. The ghost cells are left for fillGhosts.
+func (b *board) flip(cells []util.Cell) {
+	for _, cell := range cells {
+		bit := cell.X + 1
+		b.row(cell.Y)[bit>>6] ^= 1 << (bit & 63)
+	}
+}
+
+// fillGhosts refreshes the ghost cells from the cells they stand for under the board's boundary.
+// Boundaries whose ghost rows are plain copies of a row take a fast path.
+func (b *board) fillGhosts() {
+	b.fillSideGhosts()
+	switch b.boundary {
+	case Torus:
+		copy(b.row(-1), b.row(b.height-1))
+		copy(b.row(b.height), b.row(0))
+	case Reflect:
+		copy(b.row(-1), b.row(0))
+		copy(b.row(b.height), b.row(b.height-1))
+	default:
+		for _, y := range []int{-1, b.height} {
+			for x := -1; x <= b.width; x++ {
+				b.set(x, y, b.ghost(x, y))
+			}
+		}
+	}
+}
+
+// fillSideGhosts refreshes only the ghost cells at either end of each row.
+// Unless the boundary is CrossSurface these only depend on their own row,
+// so a strip of the board can refresh them without the rest of the board.
+func (b *board) fillSideGhosts() {
+	for y := 0; y < b.height; y++ {
+		b.set(-1, y, b.ghost(-1, y))
+		b.set(b.width, y, b.ghost(b.width, y))
+	}
+}
+
+// reverseRow writes the packed row src into dst mirrored left to right, ghost cells included.
+func reverseRow(dst, src []uint64, width int) {
+	for i := range dst {
+		dst[i] = 0
+	}
+	for bit := 0; bit <= width+1; bit++ {
+		if src[bit>>6]>>(bit&63)&1 == 1 {
+			mirrored := width + 1 - bit
+			dst[mirrored>>6] |= 1 << (mirrored & 63)
+		}
+	}
+}
+
+// ghost returns the state of the cell that coordinate (x, y) outside the board stands for.
+func (b *board) ghost(x, y int) bool {
+	x, y, ok := b.boundary.wrap(x, y, b.width, b.height)
+	return ok && b.get(x, y)
+}
+
+// fromBytes loads a world in the 255/0 byte representation.
+func (b *board) fromBytes(world [][]byte) {
+	for y := 0; y < b.height; y++ {
+		for x := 0; x < b.width; x++ {
+			b.set(x, y, world[y][x] == 255)
+		}
+	}
+	b.fillGhosts()
+}
+
+// toBytes converts the board back to the 255/0 byte representation used by the PGM IO.
+func (b *board) toBytes() [][]byte {
+	world := make([][]byte, b.height)
+	for y := range world {
+		world[y] = make([]byte, b.width)
+		for x := range world[y] {
+			if b.get(x, y) {
+				world[y][x] = 255
+			}
+		}
+	}
+	return world
+}
+
+func (b *board) aliveCount() int {
+	count := 0
+	for y := 0; y < b.height; y++ {
+		for i, word := range b.row(y) {
+			count += bits.OnesCount64(word & b.mask[i])
+		}
+	}
+	return count
+}
+
+func (b *board) aliveCells() []util.Cell {
+	var cells []util.Cell
+	for y := 0; y < b.height; y++ {
+		cells = appendCells(cells, b.row(y), b.mask, y)
+	}
+	return cells
+}
+
+// appendCells appends a cell for every set bit of row, in increasing x order.
+func appendCells(cells []util.Cell, row, mask []uint64, y int) []util.Cell {
+	for i, word := range row {
+		word &= mask[i]
+		for word != 0 {
+			bit := i*64 + bits.TrailingZeros64(word)
+			cells = append(cells, util.Cell{X: bit - 1, Y: y})
+			word &= word - 1
+		}
+	}
+	return cells
+}
+
+// shifted returns word i of row together with the same word shifted so that
+// every bit lines up with its west and east neighbour.
+func shifted(row []uint64, i int) (west, centre, east uint64) {
+	centre = row[i]
+	west = centre << 1
+	east = centre >> 1
+	if i > 0 {
+		west |= row[i-1] >> 63
+	}
+	if i+1 < len(row) {
+		east |= row[i+1] << 63
+	}
+	return west, centre, east
+}
+
+// fullAdd adds three one-bit numbers in every bit position at once.
+func fullAdd(a, b, c uint64) (sum, carry uint64) {
+	return a ^ b ^ c, a&b | c&(a^b)
+}
+
+// step computes rows [startY, endY) of next from b under rule, appending every cell that
+// changed state to flipped in row-major order. The ghost cells of b must be up to date.
+//
+// The eight neighbours of 64 cells are counted at once: the north and south rows are each
+// summed into two-bit counts, the west and east neighbours into another, and those are added
+// into the four bit planes s0..s3 of the 0..8 neighbour count.
+func (b *board) step(next *board, rule *ruleTable, startY, endY int, flipped []util.Cell) []util.Cell {
+	for y := startY; y < endY; y++ {
+		north, middle, south, out := b.row(y-1), b.row(y), b.row(y+1), next.row(y)
+		for i := range out {
+			nw, n, ne := shifted(north, i)
+			w, c, e := shifted(middle, i)
+			sw, s, se := shifted(south, i)
+
+			a0, a1 := fullAdd(nw, n, ne)
+			b0, b1 := fullAdd(sw, s, se)
+			c0, c1 := w^e, w&e
+
+			s0, k := fullAdd(a0, b0, c0)
+			x, z := a1^b1, c1^k
+			s1 := x ^ z
+			p, q, r := a1&b1, c1&k, x&z
+			s2 := p ^ q ^ r
+			s3 := p & q
+
+			alive := rule.apply(c, s0, s1, s2, s3) & b.mask[i]
+			out[i] = alive
+
+			for changed := (alive ^ c) & b.mask[i]; changed != 0; changed &= changed - 1 {
+				bit := i*64 + bits.TrailingZeros64(changed)
+				flipped = append(flipped, util.Cell{X: bit - 1, Y: y})
+			}
+		}
+	}
+	return flipped
+}
@@ -0,0 +1,81 @@
+package gol
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+// randomWorld returns a reproducible soup in the 255/0 byte representation.
+func randomWorld(width, height int, seed int64) [][]byte {
+	r := rand.New(rand.NewSource(seed))
+	world := make([][]byte, height)
+	for y := range world {
+		world[y] = make([]byte, width)
+		for x := range world[y] {
+			if r.Intn(3) == 0 {
+				world[y][x] = 255
+			}
+		}
+	}
+	return world
+}
+
+// TestPackedKernel checks the packed kernel against the byte kernel, including widths
+// that are not a multiple of 64 and boards split into several strips.
+func TestPackedKernel(t *testing.T) {
+	sizes := [][2]int{{16, 16}, {63, 5}, {64, 64}, {65, 17}, {130, 33}, {7, 3}, {1, 7}}
+	for _, size := range sizes {
+		width, height := size[0], size[1]
+		t.Run(fmt.Sprintf("%dx%d", width, height), func(t *testing.T) {
+			world := randomWorld(width, height, int64(width*height))
+			packed := newBoard(width, height, Torus)
+			packed.fromBytes(world)
+
+			for turn := 0; turn < 20; turn++ {
+				world, _ = calculateNextState(height, width, 0, height, world)
+				next := newBoard(width, height, Torus)
+				for _, s := range splitRows(height, 3) {
+					packed.step(next, newRuleTable(conway), s.startY, s.endY, nil)
+				}
+				next.fillGhosts()
+				packed = next
+
+				expected := packed.toBytes()
+				for y := range world {
+					for x := range world[y] {
+						if world[y][x] != expected[y][x] {
+							t.Fatalf("turn %d: cell (%d, %d) is %d in the byte kernel but %d in the packed kernel",
+								turn+1, x, y, world[y][x], expected[y][x])
+						}
+					}
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkByteKernel(b *testing.B) {
+	for _, size := range []int{512, 5120} {
+		world := randomWorld(size, size, 1)
+		b.Run(fmt.Sprintf("%dx%d", size, size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				calculateNextState(size, size, 0, size, world)
+			}
+		})
+	}
+}
+
+func BenchmarkPackedKernel(b *testing.B) {
+	for _, size := range []int{512, 5120} {
+		world := newBoard(size, size, Torus)
+		world.fromBytes(randomWorld(size, size, 1))
+		next := newBoard(size, size, Torus)
+		rule := newRuleTable(conway)
+		b.Run(fmt.Sprintf("%dx%d", size, size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				world.step(next, rule, 0, size, nil)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package gol
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Boundary selects what lies beyond the edges of the board.
+type Boundary int
+
+const (
+	// Torus joins the left edge to the right and the top edge to the bottom. This is the default.
+	Torus Boundary = iota
+	// DeadBorder surrounds the board with cells that are always dead, as on a finite plane.
+	DeadBorder
+	// Reflect mirrors the board at its edges, so the cells beyond an edge copy the cells just inside it.
+	Reflect
+	// KleinBottle joins the left and right edges as on a torus, but joins the top and bottom edges
+	// with a twist so that a pattern leaving through the top comes back mirrored left to right.
+	KleinBottle
+	// CrossSurface joins both pairs of edges with a twist (the real projective plane).
+	CrossSurface
+)
+
+var boundaryNames = map[Boundary]string{
+	Torus:        "torus",
+	DeadBorder:   "dead",
+	Reflect:      "reflect",
+	KleinBottle:  "klein",
+	CrossSurface: "cross",
+}
+
+// ParseBoundary returns the Boundary called name: torus, dead, reflect, klein or cross.
+func ParseBoundary(name string) (Boundary, error) {
+	for boundary, boundaryName := range boundaryNames {
+		if strings.EqualFold(name, boundaryName) {
+			return boundary, nil
+		}
+	}
+	return Torus, fmt.Errorf("unknown boundary %q: expected torus, dead, reflect, klein or cross", name)
+}
+
+func (boundary Boundary) String() string {
+	if name, ok := boundaryNames[boundary]; ok {
+		return name
+	}
+	return "Incorrect Boundary"
+}
+
+// wrap maps a coordinate up to one cell outside a width x height board onto the cell it stands for.
+// ok is false if there is no such cell and the coordinate is always dead.
+func (boundary Boundary) wrap(x, y, width, height int) (int, int, bool) {
+	outX := x < 0 || x >= width
+	outY := y < 0 || y >= height
+	switch boundary {
+	case DeadBorder:
+		return x, y, !outX && !outY
+	case Reflect:
+		return clamp(x, width), clamp(y, height), true
+	case KleinBottle:
+		if outY {
+			x, y = width-1-x, wrapAround(y, height)
+		}
+		return wrapAround(x, width), y, true
+	case CrossSurface:
+		if outX {
+			x, y = wrapAround(x, width), height-1-y
+		}
+		if y < 0 || y >= height {
+			x, y = width-1-x, wrapAround(y, height)
+		}
+		return x, y, true
+	default:
+		return wrapAround(x, width), wrapAround(y, height), true
+	}
+}
+
+func wrapAround(a, n int) int {
+	return (a%n + n) % n
+}
+
+func clamp(a, n int) int {
+	if a < 0 {
+		return 0
+	}
+	if a >= n {
+		return n - 1
+	}
+	return a
+}
@@ -0,0 +1,81 @@
+package gol
+
+import (
+	"testing"
+)
+
+func TestParseBoundary(t *testing.T) {
+	for boundary := Torus; boundary <= CrossSurface; boundary++ {
+		parsed, err := ParseBoundary(boundary.String())
+		if err != nil || parsed != boundary {
+			t.Errorf("ParseBoundary(%q) = %v, %v", boundary.String(), parsed, err)
+		}
+	}
+	if _, err := ParseBoundary("sphere"); err == nil {
+		t.Error("ParseBoundary(\"sphere\") should have returned an error")
+	}
+}
+
+// TestBoundaryKernel steps every boundary with the packed kernel split into strips
+// and checks the result against counting neighbours one coordinate at a time.
+func TestBoundaryKernel(t *testing.T) {
+	width, height := 67, 13
+	table := newRuleTable(conway)
+	for boundary := Torus; boundary <= CrossSurface; boundary++ {
+		t.Run(boundary.String(), func(t *testing.T) {
+			world := randomWorld(width, height, 3)
+			packed := newBoard(width, height, boundary)
+			packed.fromBytes(world)
+
+			for turn := 0; turn < 10; turn++ {
+				world = referenceStep(world, boundary, table)
+
+				next := newBoard(width, height, boundary)
+				for _, s := range splitRows(height, 4) {
+					packed.step(next, table, s.startY, s.endY, nil)
+				}
+				next.fillGhosts()
+				packed = next
+
+				actual := packed.toBytes()
+				for y := range world {
+					for x := range world[y] {
+						if world[y][x] != actual[y][x] {
+							t.Fatalf("turn %d: cell (%d, %d) should be %d, got %d", turn+1, x, y, world[y][x], actual[y][x])
+						}
+					}
+				}
+			}
+		})
+	}
+}
+
+func referenceStep(world [][]byte, boundary Boundary, table *ruleTable) [][]byte {
+	height, width := len(world), len(world[0])
+	next := make([][]byte, height)
+	for y := range next {
+		next[y] = make([]byte, width)
+		for x := range next[y] {
+			neighbours := 0
+			for dy := -1; dy <= 1; dy++ {
+				for dx := -1; dx <= 1; dx++ {
+					if dx == 0 && dy == 0 {
+						continue
+					}
+					nx, ny, ok := boundary.wrap(x+dx, y+dy, width, height)
+					if ok && world[ny][nx] == 255 {
+						neighbours++
+					}
+				}
+			}
+			alive := 0
+			if world[y][x] == 255 {
+				alive = 1
+			}
+			if table.next[alive][neighbours] {
+				next[y][x] = 255
+			}
+		}
+	}
+	return next
+}
@@ -0,0 +1,681 @@
+package gol
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/rpc"
+	"sync"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// updateQueueLength is how many turns the broker may run ahead of its controller.
+// Once the queue is full the broker waits, so a paused controller also pauses the broker.
+const updateQueueLength = 64
+
+const (
+	// rebalanceTurns is how often the broker checks whether the workers' speeds call for a new split of the rows.
+	rebalanceTurns = 32
+	// rebalanceSlack is how far a strip may be from its ideal size, as a fraction of it, before the rows are split again.
+	rebalanceSlack = 0.1
+)
+
+// remoteWorker is a worker node registered with the broker.
+type remoteWorker struct {
+	address string
+	client  *rpc.Client
+	// rate is a running average of the rows the worker computes per second, or 0 before its first turn.
+	// Only the run's loop uses it.
+	rate float64
+}
+
+// broker is the RPC service that runs a simulation across the registered worker nodes.
+//
+// By default every turn it splits the board into one strip per worker and sends each strip out
+// with its halo rows. In a halo-exchange run each worker is loaded with a strip once and the
+// workers swap edge rows among themselves, so the broker only starts each turn and collects
+// the flipped cells, which it replays onto its own copy of the board for snapshots.
+type broker struct {
+	mu       sync.Mutex
+	listener net.Listener
+	workers  []*remoteWorker
+	run      *brokerRun
+	// joined and lost hold the addresses of workers that came and went since the last turn.
+	joined, lost []string
+	// generation counts the changes to workers, so a run can tell when to split the rows again.
+	generation int
+}
+
+// brokerRun is one simulation started by a controller. It carries on when its controller
+// detaches, and a later controller may attach to it to pick up from the current turn.
+type brokerRun struct {
+	params Params
+	rule   *ruleTable
+	stop   chan struct{}
+	done   chan struct{}
+	err    error
+
+	// mu guards the fields below. The loop holds it while computing a turn,
+	// so an attaching controller always sees a whole turn.
+	mu       sync.Mutex
+	turn     int
+	world    *board
+	next     *board
+	attached *attachment
+	finished bool
+	// owners holds the worker computing each of the strips, as planned at plannedTurn from
+	// the broker's workers at generation. In a halo-exchange run loaded is set once the
+	// owners hold their strips. rebalanced is set when the plan changed during the turn.
+	owners      []*remoteWorker
+	strips      []strip
+	generation  int
+	plannedTurn int
+	loaded      bool
+	rebalanced  bool
+}
+
+// attachment is the controller following a run. The loop sends it every turn through updates,
+// which it closes once the run is finished. detached is closed when the controller leaves.
+type attachment struct {
+	updates  chan TurnUpdate
+	detached chan struct{}
+}
+
+func newAttachment() *attachment {
+	return &attachment{
+		updates:  make(chan TurnUpdate, updateQueueLength),
+		detached: make(chan struct{}),
+	}
+}
+
+// Register adds the worker node at args.Address to the pool used from the next turn on.
+func (b *broker) Register(args RegisterArgs, reply *RegisterReply) error {
+	client, err := rpc.Dial("tcp", args.Address)
+	if err != nil {
+		return err
+	}
+	b.mu.Lock()
+	b.workers = append(b.workers, &remoteWorker{address: args.Address, client: client})
+	b.joined = append(b.joined, args.Address)
+	b.generation++
+	b.mu.Unlock()
+	fmt.Println("Worker", args.Address, "registered")
+	return nil
+}
+
+// Deregister removes the worker node at args.Address once the turn in progress is complete,
+// after which the node may exit. Its rows are shared out among the other workers.
+func (b *broker) Deregister(args DeregisterArgs, reply *DeregisterReply) error {
+	b.mu.Lock()
+	run := b.run
+	b.mu.Unlock()
+	if run != nil {
+		run.mu.Lock()
+		defer run.mu.Unlock()
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for i, worker := range b.workers {
+		if worker.address == args.Address {
+			b.workers = append(b.workers[:i:i], b.workers[i+1:]...)
+			b.generation++
+			worker.client.Close()
+			fmt.Println("Worker", args.Address, "left")
+			return nil
+		}
+	}
+	return fmt.Errorf("broker: no worker registered at %v", args.Address)
+}
+
+// Start begins computing the turns of the world in args.
+func (b *broker) Start(args StartArgs, reply *StartReply) error {
+	p := args.Params
+	if len(args.Words) != (p.ImageHeight+2)*rowWords(p.ImageWidth) {
+		return errors.New("broker: world does not match the image size")
+	}
+	if p.HaloExchange && p.Boundary == CrossSurface {
+		return errors.New("broker: halo exchange cannot join the edges of a cross-surface")
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.run != nil {
+		return errors.New("broker: a run is already in progress")
+	}
+	if len(b.workers) == 0 {
+		return errors.New("broker: no workers registered")
+	}
+
+	run := &brokerRun{
+		params:   p,
+		rule:     newRuleTable(p.Rule),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+		world:    boardFromWords(p.ImageWidth, p.ImageHeight, p.Boundary, args.Words),
+		next:     newBoard(p.ImageWidth, p.ImageHeight, p.Boundary),
+		attached: newAttachment(),
+		turn:     args.CompletedTurns,
+	}
+	b.run = run
+	b.joined, b.lost = nil, nil
+	go b.loop(run)
+	return nil
+}
+
+// Updates waits for at least one turn to complete and returns every completed turn not yet collected.
+func (b *broker) Updates(args UpdatesArgs, reply *UpdatesReply) error {
+	b.mu.Lock()
+	run := b.run
+	b.mu.Unlock()
+	if run == nil {
+		return errors.New("broker: no run in progress")
+	}
+	run.mu.Lock()
+	attached := run.attached
+	run.mu.Unlock()
+	if attached == nil {
+		return errors.New("broker: no controller attached")
+	}
+
+	var update TurnUpdate
+	var ok bool
+	select {
+	case update, ok = <-attached.updates:
+	case <-attached.detached:
+		return errors.New("broker: controller detached")
+	}
+	for ok {
+		reply.Turns = append(reply.Turns, update)
+		select {
+		case update, ok = <-attached.updates:
+		default:
+			return nil
+		}
+	}
+	reply.Done = true
+	return run.err
+}
+
+// Attach makes the caller the controller of the run in progress, if there is one,
+// and returns the run's parameters with the board after its latest completed turn.
+// Updates then carries on from that turn.
+func (b *broker) Attach(args AttachArgs, reply *AttachReply) error {
+	b.mu.Lock()
+	run := b.run
+	b.mu.Unlock()
+	if run == nil {
+		return nil
+	}
+
+	run.mu.Lock()
+	defer run.mu.Unlock()
+	if run.attached != nil {
+		return errors.New("broker: another controller is attached")
+	}
+	run.attached = newAttachment()
+	if run.finished {
+		close(run.attached.updates)
+	}
+	reply.Attached = true
+	reply.Params = run.params
+	reply.CompletedTurns = run.turn
+	reply.Words = append([]uint64(nil), run.world.words...)
+	return nil
+}
+
+// Detach lets the controller leave while the run carries on without it.
+func (b *broker) Detach(args DetachArgs, reply *DetachReply) error {
+	b.mu.Lock()
+	run := b.run
+	b.mu.Unlock()
+	if run == nil {
+		return errors.New("broker: no run in progress")
+	}
+
+	run.mu.Lock()
+	defer run.mu.Unlock()
+	if run.attached != nil {
+		close(run.attached.detached)
+		run.attached = nil
+	}
+	return nil
+}
+
+// Stop ends the current run.
+func (b *broker) Stop(args StopArgs, reply *StopReply) error {
+	b.stopRun()
+	return nil
+}
+
+// Shutdown ends the current run, shuts down every registered worker and stops the broker serving.
+func (b *broker) Shutdown(args ShutdownArgs, reply *ShutdownReply) error {
+	b.stopRun()
+
+	b.mu.Lock()
+	workers := b.workers
+	b.workers = nil
+	b.mu.Unlock()
+
+	var err error
+	for _, worker := range workers {
+		if callErr := worker.client.Call(WorkerShutdown, ShutdownArgs{}, &ShutdownReply{}); callErr != nil && err == nil {
+			err = fmt.Errorf("broker: worker %v: %w", worker.address, callErr)
+		}
+		worker.client.Close()
+	}
+	b.listener.Close()
+	fmt.Println("Broker shutting down")
+	return err
+}
+
+// stopRun ends the current run, if there is one, and waits for its loop to return.
+func (b *broker) stopRun() {
+	b.mu.Lock()
+	run := b.run
+	b.run = nil
+	b.mu.Unlock()
+	if run == nil {
+		return
+	}
+	close(run.stop)
+	<-run.done
+}
+
+func (b *broker) loop(run *brokerRun) {
+	defer close(run.done)
+	defer run.finish()
+
+	for {
+		run.mu.Lock()
+		if run.turn >= run.params.Turns {
+			run.mu.Unlock()
+			return
+		}
+		flipped, err := b.step(run)
+		if err != nil {
+			run.err = err
+			run.mu.Unlock()
+			return
+		}
+		update := TurnUpdate{CompletedTurns: run.turn, Flipped: flipped}
+		if run.rebalanced {
+			update.Rebalanced, update.Strips = true, run.workerStrips()
+			run.rebalanced = false
+		}
+		b.mu.Lock()
+		update.Joined, update.Lost = b.joined, b.lost
+		b.joined, b.lost = nil, nil
+		b.mu.Unlock()
+		run.turn++
+		attached := run.attached
+		run.mu.Unlock()
+
+		// Without a controller there is nobody to wait for, so the run goes on at full speed.
+		if attached == nil {
+			select {
+			case <-run.stop:
+				return
+			default:
+			}
+			continue
+		}
+		select {
+		case attached.updates <- update:
+		case <-attached.detached:
+		case <-run.stop:
+			return
+		}
+	}
+}
+
+// finish marks the run as finished and tells the attached controller, if any.
+func (run *brokerRun) finish() {
+	run.mu.Lock()
+	defer run.mu.Unlock()
+	run.finished = true
+	if run.attached != nil {
+		close(run.attached.updates)
+	}
+}
+
+// step computes one turn on the registered workers, or on the broker itself if none are left.
+func (b *broker) step(run *brokerRun) ([]util.Cell, error) {
+	b.plan(run)
+	if run.params.HaloExchange {
+		return b.advance(run)
+	}
+	return b.distribute(run)
+}
+
+// liveWorkers returns the workers registered right now and their generation.
+func (b *broker) liveWorkers() ([]*remoteWorker, int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]*remoteWorker(nil), b.workers...), b.generation
+}
+
+// plan shares the rows out among the workers at a turn boundary, in proportion to how fast each
+// worker has been. The rows are shared out again whenever a worker joins or leaves, and every
+// rebalanceTurns turns if the strips have drifted more than rebalanceSlack from their ideal size.
+func (b *broker) plan(run *brokerRun) {
+	workers, generation := b.liveWorkers()
+	if run.generation == generation && run.turn-run.plannedTurn < rebalanceTurns {
+		return
+	}
+	run.generation, run.plannedTurn = generation, run.turn
+
+	if len(workers) == 0 {
+		if run.owners != nil {
+			run.owners, run.strips, run.loaded, run.rebalanced = nil, nil, false, true
+		}
+		return
+	}
+	strips := splitWeighted(run.world.height, speeds(workers))
+	owners := workers[:len(strips)]
+	if sameOwners(run.owners, owners) && balanced(run.strips, strips) {
+		return
+	}
+	run.owners, run.strips, run.loaded, run.rebalanced = owners, strips, false, true
+}
+
+// speeds returns the rate of each worker, taking workers that have not been timed yet to be average.
+func speeds(workers []*remoteWorker) []float64 {
+	total, timed := 0.0, 0
+	for _, worker := range workers {
+		if worker.rate > 0 {
+			total += worker.rate
+			timed++
+		}
+	}
+	average := 1.0
+	if timed > 0 {
+		average = total / float64(timed)
+	}
+	rates := make([]float64, len(workers))
+	for i, worker := range workers {
+		rates[i] = worker.rate
+		if rates[i] == 0 {
+			rates[i] = average
+		}
+	}
+	return rates
+}
+
+// measure folds a turn of rows computed by worker in elapsed into its rate.
+func (worker *remoteWorker) measure(rows int, elapsed time.Duration) {
+	if elapsed < time.Microsecond {
+		elapsed = time.Microsecond
+	}
+	rate := float64(rows) / elapsed.Seconds()
+	if worker.rate == 0 {
+		worker.rate = rate
+	} else {
+		worker.rate = (worker.rate + rate) / 2
+	}
+}
+
+func sameOwners(a, b []*remoteWorker) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// balanced reports whether every strip in current is within rebalanceSlack of its size in ideal.
+func balanced(current, ideal []strip) bool {
+	if len(current) != len(ideal) {
+		return false
+	}
+	for i := range ideal {
+		have := current[i].endY - current[i].startY
+		want := ideal[i].endY - ideal[i].startY
+		slack := int(rebalanceSlack * float64(want))
+		if slack < 1 {
+			slack = 1
+		}
+		if have-want > slack || want-have > slack {
+			return false
+		}
+	}
+	return true
+}
+
+// workerStrips describes the plan for the controller.
+func (run *brokerRun) workerStrips() []WorkerStrip {
+	strips := make([]WorkerStrip, len(run.owners))
+	for i, owner := range run.owners {
+		strips[i] = WorkerStrip{Address: owner.address, StartY: run.strips[i].startY, EndY: run.strips[i].endY}
+	}
+	return strips
+}
+
+// distribute sends every worker its strip of the board with the rows either side of it,
+// then stitches the new strips together and swaps the run's boards.
+// A strip whose worker cannot be reached is computed again by another worker.
+func (b *broker) distribute(run *brokerRun) ([]util.Cell, error) {
+	if len(run.owners) == 0 {
+		return run.local(), nil
+	}
+
+	world, next := run.world, run.next
+	calls := make([]*rpc.Call, len(run.strips))
+	replies := make([]StepReply, len(run.strips))
+	for i, s := range run.strips {
+		calls[i] = run.owners[i].client.Go(WorkerStep, run.stepArgs(s), &replies[i], nil)
+	}
+
+	var flipped []util.Cell
+	for i, s := range run.strips {
+		owner := run.owners[i]
+		err := wait(calls[i])
+		if err != nil && !unreachable(err) {
+			return nil, fmt.Errorf("broker: worker %v: %w", owner.address, err)
+		}
+		if err != nil {
+			b.lose(owner, err)
+			flipped = append(flipped, b.redo(run, s)...)
+			continue
+		}
+		owner.measure(s.endY-s.startY, replies[i].Elapsed)
+		copy(next.words[(s.startY+1)*next.stride:(s.endY+1)*next.stride], replies[i].Rows)
+		flipped = append(flipped, replies[i].Flipped...)
+	}
+	next.fillGhosts()
+	run.world, run.next = next, world
+	return flipped, nil
+}
+
+// redo computes strip s again after its worker was lost, on the first worker that answers
+// or on the broker itself if none do. The new rows go straight into run.next.
+func (b *broker) redo(run *brokerRun, s strip) []util.Cell {
+	workers, _ := b.liveWorkers()
+	for _, worker := range workers {
+		var reply StepReply
+		if err := wait(worker.client.Go(WorkerStep, run.stepArgs(s), &reply, nil)); err != nil {
+			b.lose(worker, err)
+			continue
+		}
+		copy(run.next.words[(s.startY+1)*run.next.stride:(s.endY+1)*run.next.stride], reply.Rows)
+		return reply.Flipped
+	}
+	return run.world.step(run.next, run.rule, s.startY, s.endY, nil)
+}
+
+func (run *brokerRun) stepArgs(s strip) StepArgs {
+	world := run.world
+	return StepArgs{
+		Width:  world.width,
+		StartY: s.startY,
+		EndY:   s.endY,
+		Rule:   run.params.Rule,
+		Rows:   world.words[s.startY*world.stride : (s.endY+2)*world.stride],
+	}
+}
+
+// local computes a turn on the broker itself, for when there are no workers left to do it.
+func (run *brokerRun) local() []util.Cell {
+	world, next := run.world, run.next
+	world.fillGhosts()
+	flipped := world.step(next, run.rule, 0, world.height, nil)
+	next.fillGhosts()
+	run.world, run.next = next, world
+	return flipped
+}
+
+// load hands every owner its strip of the board to keep for a halo-exchange run,
+// telling it which workers own the strips above and below.
+// It reports false if a worker could not be reached.
+func (b *broker) load(run *brokerRun) (bool, error) {
+	world := run.world
+	world.fillGhosts()
+	owners := run.owners
+	calls := make([]*rpc.Call, len(run.strips))
+	for i, s := range run.strips {
+		args := LoadArgs{
+			Width:          world.width,
+			Height:         world.height,
+			Boundary:       world.boundary,
+			Rule:           run.params.Rule,
+			StartY:         s.startY,
+			EndY:           s.endY,
+			CompletedTurns: run.turn,
+			Rows:           world.words[(s.startY+1)*world.stride : (s.endY+1)*world.stride],
+			Above:          owners[(i+len(owners)-1)%len(owners)].address,
+			Below:          owners[(i+1)%len(owners)].address,
+		}
+		calls[i] = owners[i].client.Go(WorkerLoad, args, &LoadReply{}, nil)
+	}
+	ok, err := b.gather(owners, calls)
+	run.loaded = ok
+	return ok, err
+}
+
+// advance moves every loaded strip on by one turn and replays the flipped cells onto the run's board.
+// If a worker is lost the rows are shared out again among the workers that are left, loaded
+// from the run's board, and the turn is tried again.
+func (b *broker) advance(run *brokerRun) ([]util.Cell, error) {
+	for {
+		if len(run.owners) == 0 {
+			return run.local(), nil
+		}
+		if !run.loaded {
+			ok, err := b.load(run)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				b.plan(run)
+				continue
+			}
+		}
+
+		calls := make([]*rpc.Call, len(run.owners))
+		replies := make([]AdvanceReply, len(run.owners))
+		for i, owner := range run.owners {
+			calls[i] = owner.client.Go(WorkerAdvance, AdvanceArgs{CompletedTurns: run.turn}, &replies[i], nil)
+		}
+		ok, err := b.gather(run.owners, calls)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			// Some strips may have moved on already, so they all start again from the run's board.
+			run.loaded = false
+			b.plan(run)
+			continue
+		}
+
+		var flipped []util.Cell
+		for i, reply := range replies {
+			s := run.strips[i]
+			run.owners[i].measure(s.endY-s.startY, reply.Elapsed)
+			flipped = append(flipped, reply.Flipped...)
+		}
+		for _, cell := range flipped {
+			run.world.set(cell.X, cell.Y, !run.world.get(cell.X, cell.Y))
+		}
+		return flipped, nil
+	}
+}
+
+// gather waits for calls made to workers, dropping any worker that cannot be reached.
+// It reports whether every call went through. The workers left behind may fail because
+// a neighbour was lost, so an error is returned only when every worker could be reached.
+func (b *broker) gather(workers []*remoteWorker, calls []*rpc.Call) (bool, error) {
+	lost := false
+	var failed error
+	for i, call := range calls {
+		err := wait(call)
+		switch {
+		case err == nil:
+		case unreachable(err):
+			b.lose(workers[i], err)
+			lost = true
+		case failed == nil:
+			failed = fmt.Errorf("broker: worker %v: %w", workers[i].address, err)
+		}
+	}
+	if lost {
+		return false, nil
+	}
+	return failed == nil, failed
+}
+
+// lose drops a worker that could not be reached. The controller hears about it with the next turn.
+func (b *broker) lose(worker *remoteWorker, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for i, w := range b.workers {
+		if w == worker {
+			b.workers = append(b.workers[:i:i], b.workers[i+1:]...)
+			b.lost = append(b.lost, worker.address)
+			b.generation++
+			worker.client.Close()
+			fmt.Println("Worker", worker.address, "lost:", err)
+			return
+		}
+	}
+}
+
+// heartbeat pings every worker each heartbeatInterval until quit is closed,
+// so a worker that dies between runs or while the run is paused is noticed too.
+func (b *broker) heartbeat(quit <-chan struct{}) {
+	ticker := time.NewTicker(heartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-quit:
+			return
+		case <-ticker.C:
+		}
+		workers, _ := b.liveWorkers()
+		for _, worker := range workers {
+			if err := wait(worker.client.Go(WorkerPing, PingArgs{}, &PingReply{}, nil)); err != nil {
+				b.lose(worker, err)
+			}
+		}
+	}
+}
+
+// ServeBroker runs a broker on listener until the listener is closed, either directly or by a
+// Shutdown call, and every client has hung up.
+func ServeBroker(listener net.Listener) error {
+	b := &broker{listener: listener}
+	server := rpc.NewServer()
+	if err := server.RegisterName("Broker", b); err != nil {
+		return err
+	}
+	quit := make(chan struct{})
+	go b.heartbeat(quit)
+	serve(server, listener)
+	close(quit)
+	return nil
+}
@@ -0,0 +1,347 @@
+package gol
+
+import (
+	"reflect"
+	"sort"
+	"sync"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// Policy is what a subscription to a Bus does with an event when its buffer is full.
+type Policy int
+
+const (
+	// Block waits for room in the buffer, holding up the bus and so the run.
+	Block Policy = iota
+	// DropOldest drops the oldest event in the buffer to make room, counting it in Dropped.
+	DropOldest
+	// CoalesceFlips merges the flip events and TurnCompletes that arrive while the buffer is full into one
+	// CellsFlipped and the latest TurnComplete, delivered once the buffer has emptied, so the board the subscriber
+	// keeps skips ahead rather than falls behind. Other events wait for them to be delivered, as with Block.
+	CoalesceFlips
+)
+
+// Filter picks the events a subscription to a Bus is sent.
+type Filter struct {
+	// Types holds an event of each type wanted, such as TurnComplete{}, or is empty for every type.
+	Types []Event
+	// Every passes only the TurnComplete of every Every'th turn when above 1. The cells flipped in the turns
+	// in between are merged into one CellsFlipped sent before it, or before any other event passed.
+	Every int
+}
+
+// wants reports whether event is of one of the types the filter picks.
+func (f Filter) wants(event Event) bool {
+	if len(f.Types) == 0 {
+		return true
+	}
+	for _, t := range f.Types {
+		if reflect.TypeOf(t) == reflect.TypeOf(event) {
+			return true
+		}
+	}
+	return false
+}
+
+// Bus passes the events of a run on to any number of subscribers, such as the SDL window, a GIF recorder
+// and a logger, each with its own buffer and filter. The zero value is an empty bus ready to use.
+type Bus struct {
+	mu            sync.Mutex
+	subscriptions []*Subscription
+	ended         bool
+}
+
+// Subscribe returns a subscription to the events of the bus picked by filter, with a buffer of size events
+// that is dealt with by policy when full. Subscribe before the events are forwarded so as not to miss any.
+func (b *Bus) Subscribe(filter Filter, size int, policy Policy) *Subscription {
+	if size < 1 {
+		size = 1
+	}
+	s := &Subscription{
+		bus:    b,
+		filter: filter,
+		size:   size,
+		policy: policy,
+		events: make(chan Event),
+		done:   make(chan struct{}),
+	}
+	s.changed = sync.NewCond(&s.mu)
+	go s.deliver()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.ended {
+		s.end()
+	} else {
+		b.subscriptions = append(b.subscriptions, s)
+	}
+	return s
+}
+
+// Forward publishes every event sent on events to the subscribers until events is closed, and then ends
+// every subscription, closing its channel once the events left in its buffer have been delivered.
+func (b *Bus) Forward(events <-chan Event) {
+	for event := range events {
+		b.mu.Lock()
+		subscriptions := append([]*Subscription(nil), b.subscriptions...)
+		b.mu.Unlock()
+		for _, s := range subscriptions {
+			s.publish(event)
+		}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.ended = true
+	for _, s := range b.subscriptions {
+		s.end()
+	}
+	b.subscriptions = nil
+}
+
+// Subscription is a subscriber's share of the events on a Bus.
+type Subscription struct {
+	bus    *Bus
+	filter Filter
+	size   int
+	policy Policy
+	events chan Event
+	done   chan struct{}
+
+	mu      sync.Mutex
+	changed *sync.Cond
+	queue   []Event
+	// skipped holds the cells flipped in the turns Filter.Every has passed over, and merged
+	// the events CoalesceFlips has merged while the buffer was full.
+	skipped, merged flipMerge
+	dropped         int
+	ended           bool
+	cancelled       bool
+}
+
+// Events returns the channel the subscription's events are delivered on, which is closed once
+// the bus has ended and the buffer is empty, or once Unsubscribe is called.
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// Dropped returns the number of events DropOldest has dropped so far.
+func (s *Subscription) Dropped() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.dropped
+}
+
+// Unsubscribe stops the subscription, dropping any events in its buffer and closing its channel,
+// so a subscriber that stops reading early does not hold up the bus. It may be called more than once.
+func (s *Subscription) Unsubscribe() {
+	b := s.bus
+	b.mu.Lock()
+	for i, other := range b.subscriptions {
+		if other == s {
+			b.subscriptions = append(b.subscriptions[:i], b.subscriptions[i+1:]...)
+			break
+		}
+	}
+	b.mu.Unlock()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.cancelled {
+		s.cancelled = true
+		s.queue = nil
+		close(s.done)
+		s.changed.Broadcast()
+	}
+}
+
+// publish filters event and adds it to the buffer.
+func (s *Subscription) publish(event Event) {
+	if !s.filter.wants(event) {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.filter.Every > 1 {
+		switch e := event.(type) {
+		case CellFlipped, CellsFlipped, RowsFlipped:
+			s.skipped.add(event)
+			return
+		case TurnComplete:
+			if e.CompletedTurns%s.filter.Every != 0 {
+				return
+			}
+		}
+		for _, flipped := range s.skipped.take() {
+			s.push(flipped)
+		}
+	}
+	s.push(event)
+}
+
+// push adds event to the buffer, dealing with a full buffer by the subscription's policy.
+// It must be called with s.mu held.
+func (s *Subscription) push(event Event) {
+	full := func() bool {
+		return len(s.queue) >= s.size || (s.policy == CoalesceFlips && !s.merged.empty())
+	}
+	switch {
+	case s.cancelled:
+		return
+	case s.policy == CoalesceFlips && full() && s.merged.add(event):
+		s.changed.Broadcast()
+		return
+	case s.policy == DropOldest && len(s.queue) >= s.size:
+		s.queue[0] = nil
+		s.queue = s.queue[1:]
+		s.dropped++
+	}
+	for full() && !s.cancelled {
+		s.changed.Wait()
+	}
+	if !s.cancelled {
+		s.queue = append(s.queue, owned(event))
+		s.changed.Broadcast()
+	}
+}
+
+// owned returns event with its own copy of the cells of a flip event, as the run reuses them
+// while the event may still be in the buffer.
+func owned(event Event) Event {
+	switch e := event.(type) {
+	case CellsFlipped:
+		e.Cells = append([]util.Cell(nil), e.Cells...)
+		return e
+	case RowsFlipped:
+		e.Runs = append([]FlipRun(nil), e.Runs...)
+		return e
+	}
+	return event
+}
+
+// end marks the subscription as having no more events to come.
+func (s *Subscription) end() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ended = true
+	s.changed.Broadcast()
+}
+
+// deliver sends the events in the buffer, then any that were merged, on the subscription's channel,
+// and closes it once the subscription has ended and every event has been delivered.
+func (s *Subscription) deliver() {
+	defer close(s.events)
+	for {
+		s.mu.Lock()
+		for len(s.queue) == 0 && s.merged.empty() && !s.ended && !s.cancelled {
+			s.changed.Wait()
+		}
+		if s.cancelled || (len(s.queue) == 0 && s.merged.empty()) {
+			s.mu.Unlock()
+			return
+		}
+		var next []Event
+		if len(s.queue) > 0 {
+			next = []Event{s.queue[0]}
+			s.queue[0] = nil
+			s.queue = s.queue[1:]
+		} else {
+			next = s.merged.take()
+		}
+		s.changed.Broadcast()
+		s.mu.Unlock()
+
+		for _, event := range next {
+			select {
+			case s.events <- event:
+			case <-s.done:
+				return
+			}
+		}
+	}
+}
+
+// flipMerge gathers flip events and TurnCompletes, where a cell flipped twice is back as it was.
+// cells holds the cells flipped up to the latest TurnComplete, or all of them if there is none,
+// and later those flipped after it.
+type flipMerge struct {
+	cells, later           map[util.Cell]bool
+	cellsTurns, laterTurns int
+	turn                   *TurnComplete
+}
+
+// add merges event in if it is a flip event or TurnComplete, and reports whether it was.
+func (m *flipMerge) add(event Event) bool {
+	var cells []util.Cell
+	switch e := event.(type) {
+	case CellFlipped:
+		cells = []util.Cell{e.Cell}
+	case CellsFlipped:
+		cells = e.Cells
+	case RowsFlipped:
+		cells = e.Cells()
+	case TurnComplete:
+		for cell := range m.later {
+			m.flip(&m.cells, cell)
+		}
+		if len(m.later) > 0 {
+			m.cellsTurns = m.laterTurns
+		}
+		m.later = nil
+		m.turn = &e
+		return true
+	default:
+		return false
+	}
+
+	if m.turn == nil {
+		m.cellsTurns = event.GetCompletedTurns()
+		for _, cell := range cells {
+			m.flip(&m.cells, cell)
+		}
+	} else {
+		m.laterTurns = event.GetCompletedTurns()
+		for _, cell := range cells {
+			m.flip(&m.later, cell)
+		}
+	}
+	return true
+}
+
+func (m *flipMerge) flip(cells *map[util.Cell]bool, cell util.Cell) {
+	if *cells == nil {
+		*cells = make(map[util.Cell]bool)
+	}
+	if (*cells)[cell] {
+		delete(*cells, cell)
+	} else {
+		(*cells)[cell] = true
+	}
+}
+
+func (m *flipMerge) empty() bool {
+	return len(m.cells) == 0 && len(m.later) == 0 && m.turn == nil
+}
+
+// take returns the cells flipped up to the latest TurnComplete in a CellsFlipped, in order of row
+// and then column, followed by the TurnComplete, and keeps any flipped after it for the next take.
+func (m *flipMerge) take() []Event {
+	var events []Event
+	if len(m.cells) > 0 {
+		cells := make([]util.Cell, 0, len(m.cells))
+		for cell := range m.cells {
+			cells = append(cells, cell)
+		}
+		sort.Slice(cells, func(i, j int) bool {
+			return cells[i].Y < cells[j].Y || (cells[i].Y == cells[j].Y && cells[i].X < cells[j].X)
+		})
+		events = append(events, CellsFlipped{CompletedTurns: m.cellsTurns, Cells: cells})
+	}
+	if m.turn != nil {
+		events = append(events, *m.turn)
+	}
+	*m = flipMerge{cells: m.later, cellsTurns: m.laterTurns}
+	return events
+}
@@ -0,0 +1,185 @@
+package gol
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// busEvents returns the events of a run of turns turns on a width by width board, flipping random cells
+// in a CellsFlipped and a RowsFlipped before each TurnComplete and ending with a FinalTurnComplete.
+func busEvents(width, turns int) []Event {
+	r := rand.New(rand.NewSource(int64(turns)))
+	cell := func() util.Cell { return util.Cell{X: r.Intn(width), Y: r.Intn(width)} }
+	events := []Event{StateChange{NewState: Executing}}
+	for turn := 0; turn < turns; turn++ {
+		events = append(events,
+			CellsFlipped{CompletedTurns: turn, Cells: []util.Cell{cell(), cell()}},
+			RowsFlipped{CompletedTurns: turn, Runs: []FlipRun{{X: 0, Y: r.Intn(width), Length: 1 + r.Intn(width)}}},
+			TurnComplete{CompletedTurns: turn + 1})
+	}
+	return append(events, FinalTurnComplete{CompletedTurns: turns})
+}
+
+// forward sends events through a bus with the subscriptions subscribe makes, returning them and a channel
+// closed once the bus has forwarded every event.
+func forward(events []Event, subscribe func(b *Bus) []*Subscription) ([]*Subscription, <-chan struct{}) {
+	var b Bus
+	subscriptions := subscribe(&b)
+	sent := make(chan Event)
+	done := make(chan struct{})
+	go func() {
+		b.Forward(sent)
+		close(done)
+	}()
+	go func() {
+		for _, event := range events {
+			sent <- event
+		}
+		close(sent)
+	}()
+	return subscriptions, done
+}
+
+// busBoard returns the cells left alive by the flip events in events, and the turns of its TurnCompletes.
+func busBoard(events []Event) (map[util.Cell]bool, []int) {
+	alive := make(map[util.Cell]bool)
+	var turns []int
+	for _, event := range events {
+		var cells []util.Cell
+		switch e := event.(type) {
+		case CellsFlipped:
+			cells = e.Cells
+		case RowsFlipped:
+			cells = e.Cells()
+		case TurnComplete:
+			turns = append(turns, e.CompletedTurns)
+		}
+		for _, cell := range cells {
+			if alive[cell] {
+				delete(alive, cell)
+			} else {
+				alive[cell] = true
+			}
+		}
+	}
+	return alive, turns
+}
+
+func collect(s *Subscription, delay time.Duration) []Event {
+	var events []Event
+	for event := range s.Events() {
+		events = append(events, event)
+		time.Sleep(delay)
+	}
+	return events
+}
+
+// TestBus checks that every subscriber to a bus is sent the events its filter picks, in order,
+// and that the policies for a full buffer keep the board each subscriber builds right.
+func TestBus(t *testing.T) {
+	const width, turns = 16, 100
+	events := busEvents(width, turns)
+	alive, _ := busBoard(events)
+
+	t.Run("block", func(t *testing.T) {
+		subscriptions, _ := forward(events, func(b *Bus) []*Subscription {
+			return []*Subscription{
+				b.Subscribe(Filter{}, 1, Block),
+				b.Subscribe(Filter{Types: []Event{TurnComplete{}, FinalTurnComplete{}}}, 1, Block),
+			}
+		})
+		all := make(chan []Event)
+		go func() { all <- collect(subscriptions[0], 0) }()
+		turnsOnly := collect(subscriptions[1], 0)
+
+		if got := <-all; !reflect.DeepEqual(got, events) {
+			t.Errorf("got %v events, expected all %v in order", len(got), len(events))
+		}
+		if len(turnsOnly) != turns+1 {
+			t.Errorf("got %v events of the types filtered for, expected %v", len(turnsOnly), turns+1)
+		}
+		for _, event := range turnsOnly {
+			switch event.(type) {
+			case TurnComplete, FinalTurnComplete:
+			default:
+				t.Errorf("got a %T event, expected only TurnComplete and FinalTurnComplete", event)
+			}
+		}
+	})
+
+	t.Run("every", func(t *testing.T) {
+		const every = 10
+		subscriptions, _ := forward(events, func(b *Bus) []*Subscription {
+			return []*Subscription{b.Subscribe(Filter{Every: every}, 10, Block)}
+		})
+		got := collect(subscriptions[0], 0)
+		board, gotTurns := busBoard(got)
+		if !reflect.DeepEqual(board, alive) {
+			t.Errorf("the flips sent every %v turns leave %v cells alive, expected %v", every, len(board), len(alive))
+		}
+		for i, turn := range gotTurns {
+			if turn != (i+1)*every {
+				t.Fatalf("got TurnCompletes for turns %v, expected every %vth", gotTurns, every)
+			}
+		}
+		if len(got) != 2+2*turns/every {
+			t.Errorf("got %v events, expected one CellsFlipped before each TurnComplete", len(got))
+		}
+	})
+
+	t.Run("drop oldest", func(t *testing.T) {
+		subscriptions, done := forward(events, func(b *Bus) []*Subscription {
+			return []*Subscription{b.Subscribe(Filter{}, 5, DropOldest)}
+		})
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("the bus was held up by a subscriber that drops the oldest events")
+		}
+		s := subscriptions[0]
+		got := collect(s, 0)
+		if len(got)+s.Dropped() != len(events) {
+			t.Errorf("got %v events and dropped %v, expected %v in all", len(got), s.Dropped(), len(events))
+		}
+		if last := got[len(got)-5:]; !reflect.DeepEqual(last, events[len(events)-5:]) {
+			t.Errorf("got last events %v, expected the last five sent", last)
+		}
+	})
+
+	t.Run("coalesce flips", func(t *testing.T) {
+		subscriptions, _ := forward(events, func(b *Bus) []*Subscription {
+			return []*Subscription{b.Subscribe(Filter{}, 2, CoalesceFlips)}
+		})
+		got := collect(subscriptions[0], time.Millisecond)
+		board, gotTurns := busBoard(got)
+		if !reflect.DeepEqual(board, alive) {
+			t.Errorf("the coalesced flips leave %v cells alive, expected %v", len(board), len(alive))
+		}
+		if len(got) >= len(events) {
+			t.Errorf("got %v events from a slow subscriber, expected fewer than the %v sent", len(got), len(events))
+		}
+		if len(gotTurns) == 0 || gotTurns[len(gotTurns)-1] != turns {
+			t.Errorf("got TurnCompletes for turns %v, expected them to end at %v", gotTurns, turns)
+		}
+		if _, ok := got[len(got)-1].(FinalTurnComplete); !ok {
+			t.Errorf("got %T as the last event, expected FinalTurnComplete", got[len(got)-1])
+		}
+	})
+
+	t.Run("unsubscribe", func(t *testing.T) {
+		subscriptions, done := forward(events, func(b *Bus) []*Subscription {
+			return []*Subscription{b.Subscribe(Filter{}, 1, Block)}
+		})
+		subscriptions[0].Unsubscribe()
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("the bus was held up by a subscriber that had unsubscribed")
+		}
+		collect(subscriptions[0], 0)
+	})
+}
@@ -0,0 +1,73 @@
+package gol
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// readCells reads a pattern in the plaintext .cells format: lines starting with ! are comments,
+// and every other line is a row of the pattern with . for a dead cell and O for a live one.
+// Rows may stop short, leaving the rest of the row dead, so the widest row sets the width.
+func readCells(r io.Reader) (*pattern, error) {
+	scanner := bufio.NewScanner(r)
+	p := &pattern{}
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if strings.HasPrefix(line, "!") {
+			continue
+		}
+		for x, c := range line {
+			switch c {
+			case '.':
+			case 'O', '*':
+				p.alive = append(p.alive, util.Cell{X: x, Y: p.height})
+			default:
+				return nil, fmt.Errorf("cells: unexpected %q in row %v", c, p.height+1)
+			}
+		}
+		if len(line) > p.width {
+			p.width = len(line)
+		}
+		p.height++
+		if p.width > maxPgmSize || p.height > maxPgmSize {
+			return nil, fmt.Errorf("cells: the pattern is bigger than %vx%v", maxPgmSize, maxPgmSize)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if p.width == 0 {
+		return nil, fmt.Errorf("cells: no rows")
+	}
+	return p, nil
+}
+
+// writeCells writes world, a grid of 255/0 bytes, in the plaintext .cells format.
+// Every row is written out in full so that the pattern reads back at the same size.
+// The format has no place for the rule.
+func writeCells(w io.Writer, world [][]byte, rule Rule) error {
+	out := bufio.NewWriter(w)
+	fmt.Fprintf(out, "!Name: %vx%v board\n", rowLength(world), len(world))
+	for _, row := range world {
+		for _, cell := range row {
+			if cell == 255 {
+				out.WriteByte('O')
+			} else {
+				out.WriteByte('.')
+			}
+		}
+		out.WriteByte('\n')
+	}
+	return out.Flush()
+}
+
+func rowLength(world [][]byte) int {
+	if len(world) == 0 {
+		return 0
+	}
+	return len(world[0])
+}
@@ -0,0 +1,51 @@
+package gol
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestReadCells(t *testing.T) {
+	glider := "!Name: Glider\n!\n.O\n..O\nOOO\n"
+	p, err := readCells(strings.NewReader(glider))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.width != 3 || p.height != 3 || fmt.Sprint(p.alive) != "[{1 0} {2 1} {0 2} {1 2} {2 2}]" {
+		t.Fatalf("got a %vx%v pattern with cells %v", p.width, p.height, p.alive)
+	}
+
+	for _, bad := range []string{"", "!only a comment\n", ".O\n.X\n", strings.Repeat(".\n", maxPgmSize+1)} {
+		if _, err := readCells(strings.NewReader(bad)); err == nil {
+			t.Errorf("expected an error reading %q", bad)
+		}
+	}
+}
+
+func TestCellsRoundTrip(t *testing.T) {
+	for _, size := range [][2]int{{16, 16}, {1, 1}, {70, 9}} {
+		width, height := size[0], size[1]
+		world := randomWorld(width, height, int64(height))
+		for x := range world[height-1] {
+			world[height-1][x] = 0
+		}
+
+		var b bytes.Buffer
+		if err := writeCells(&b, world, Rule{}); err != nil {
+			t.Fatal(err)
+		}
+		p, err := readCells(&b)
+		if err != nil {
+			t.Fatalf("%dx%d: %v", width, height, err)
+		}
+		got, err := p.centre(width, height)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(bytes.Join(got, nil), bytes.Join(world, nil)) {
+			t.Fatalf("%dx%d: the board read back differs from the one written", width, height)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package gol
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// checkpointExtension is the file extension of checkpoints, which Params.Input can name to resume a run.
+const checkpointExtension = ".ckpt"
+
+// checkpointMagic starts every checkpoint, with the version of the format.
+var checkpointMagic = [8]byte{'G', 'O', 'L', 'C', 'K', 'P', 'T', '1'}
+
+// The errors returned for a checkpoint that cannot be resumed from.
+var (
+	ErrCheckpoint = errors.New("malformed checkpoint")
+	ErrChecksum   = errors.New("checkpoint checksum does not match")
+)
+
+// checkpoint is everything needed to resume a run: the board after turn completed turns,
+// and the rule and boundary it was computed with.
+type checkpoint struct {
+	turn     int
+	rule     Rule
+	boundary Boundary
+	world    [][]byte
+}
+
+// checkpointHeader is how the header of a checkpoint is laid out in the file, in little endian.
+// The rows of the board follow it, a bit per cell and padded to whole bytes, and then the
+// IEEE CRC-32 of everything before it.
+type checkpointHeader struct {
+	Magic          [8]byte
+	Width, Height  uint32
+	Turn           uint64
+	Birth, Survive uint16
+	Boundary       uint8
+}
+
+// isCheckpointFile reports whether path names a checkpoint.
+func isCheckpointFile(path string) bool {
+	return filepath.Ext(path) == checkpointExtension
+}
+
+// writeCheckpoint writes c, a board of 255/0 bytes, in the checkpoint format.
+func writeCheckpoint(w io.Writer, c checkpoint) error {
+	out := bufio.NewWriter(w)
+	sum := crc32.NewIEEE()
+	body := io.MultiWriter(out, sum)
+
+	header := checkpointHeader{
+		Magic:    checkpointMagic,
+		Height:   uint32(len(c.world)),
+		Turn:     uint64(c.turn),
+		Birth:    c.rule.Birth,
+		Survive:  c.rule.Survive,
+		Boundary: uint8(c.boundary),
+	}
+	if len(c.world) > 0 {
+		header.Width = uint32(len(c.world[0]))
+	}
+	if err := binary.Write(body, binary.LittleEndian, header); err != nil {
+		return err
+	}
+	row := make([]byte, (header.Width+7)/8)
+	for _, cells := range c.world {
+		for i := range row {
+			row[i] = 0
+		}
+		for x, cell := range cells {
+			if cell == 255 {
+				row[x/8] |= 1 << (x % 8)
+			}
+		}
+		if _, err := body.Write(row); err != nil {
+			return err
+		}
+	}
+	if err := binary.Write(out, binary.LittleEndian, sum.Sum32()); err != nil {
+		return err
+	}
+	return out.Flush()
+}
+
+// readCheckpoint reads a checkpoint and checks its checksum.
+func readCheckpoint(r io.Reader) (*checkpoint, error) {
+	sum := crc32.NewIEEE()
+	body := io.TeeReader(bufio.NewReader(r), sum)
+
+	var header checkpointHeader
+	if err := binary.Read(body, binary.LittleEndian, &header); err != nil {
+		return nil, fmt.Errorf("%w: reading the header: %v", ErrCheckpoint, err)
+	}
+	if header.Magic != checkpointMagic {
+		return nil, fmt.Errorf("%w: not a checkpoint", ErrCheckpoint)
+	}
+	if header.Width == 0 || header.Height == 0 || header.Width > maxPgmSize || header.Height > maxPgmSize {
+		return nil, fmt.Errorf("%w: a %vx%v board", ErrCheckpoint, header.Width, header.Height)
+	}
+	if _, ok := boundaryNames[Boundary(header.Boundary)]; !ok {
+		return nil, fmt.Errorf("%w: unknown boundary %v", ErrCheckpoint, header.Boundary)
+	}
+
+	c := &checkpoint{
+		turn:     int(header.Turn),
+		rule:     Rule{Birth: header.Birth, Survive: header.Survive},
+		boundary: Boundary(header.Boundary),
+	}
+	width := int(header.Width)
+	row := make([]byte, (width+7)/8)
+	for y := 0; y < int(header.Height); y++ {
+		if _, err := io.ReadFull(body, row); err != nil {
+			return nil, fmt.Errorf("%w: reading row %v: %v", ErrCheckpoint, y, err)
+		}
+		cells := make([]byte, width)
+		for x := range cells {
+			if row[x/8]&(1<<(x%8)) != 0 {
+				cells[x] = 255
+			}
+		}
+		c.world = append(c.world, cells)
+	}
+
+	expected := sum.Sum32()
+	var stored uint32
+	if err := binary.Read(body, binary.LittleEndian, &stored); err != nil {
+		return nil, fmt.Errorf("%w: reading the checksum: %v", ErrCheckpoint, err)
+	}
+	if stored != expected {
+		return nil, ErrChecksum
+	}
+	return c, nil
+}
+
+// readCheckpointFile reads the checkpoint at path.
+func readCheckpointFile(path string) (*checkpoint, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	c, err := readCheckpoint(file)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", path, err)
+	}
+	return c, nil
+}
+
+// writeCheckpointFile writes c to path. It writes to a temporary file first and renames it,
+// so a run stopped while writing leaves the last checkpoint as it was.
+func writeCheckpointFile(path string, c checkpoint) error {
+	temp := path + ".tmp"
+	file, err := os.Create(temp)
+	if err != nil {
+		return err
+	}
+	if err := writeCheckpoint(file, c); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(temp, path)
+}
@@ -0,0 +1,54 @@
+package gol
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestCheckpointRoundTrip(t *testing.T) {
+	for _, size := range [][2]int{{16, 16}, {13, 7}, {1, 1}, {100, 3}} {
+		c := checkpoint{
+			turn:     1 << 40,
+			rule:     Rule{Birth: 1<<3 | 1<<6, Survive: 1<<2 | 1<<3},
+			boundary: KleinBottle,
+			world:    randomWorld(size[0], size[1], int64(size[0])),
+		}
+		var b bytes.Buffer
+		if err := writeCheckpoint(&b, c); err != nil {
+			t.Fatal(err)
+		}
+		read, err := readCheckpoint(&b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if read.turn != c.turn || read.rule != c.rule || read.boundary != c.boundary {
+			t.Fatalf("got turn %v, rule %v and boundary %v, expected %v, %v and %v",
+				read.turn, read.rule, read.boundary, c.turn, c.rule, c.boundary)
+		}
+		if !bytes.Equal(bytes.Join(read.world, nil), bytes.Join(c.world, nil)) {
+			t.Fatalf("the %vx%v board did not survive the round trip", size[0], size[1])
+		}
+	}
+}
+
+// TestCheckpointCorrupt checks that a checkpoint with any byte changed, or cut short, is rejected.
+func TestCheckpointCorrupt(t *testing.T) {
+	var b bytes.Buffer
+	if err := writeCheckpoint(&b, checkpoint{turn: 7, world: randomWorld(20, 10, 1)}); err != nil {
+		t.Fatal(err)
+	}
+	data := b.Bytes()
+	for i := range data {
+		corrupt := append([]byte(nil), data...)
+		corrupt[i] ^= 0x10
+		if _, err := readCheckpoint(bytes.NewReader(corrupt)); !errors.Is(err, ErrChecksum) && !errors.Is(err, ErrCheckpoint) {
+			t.Fatalf("changing byte %v gave the error %v", i, err)
+		}
+	}
+	for _, n := range []int{0, 10, len(data) / 2, len(data) - 1} {
+		if _, err := readCheckpoint(bytes.NewReader(data[:n])); !errors.Is(err, ErrCheckpoint) {
+			t.Errorf("cutting the checkpoint to %v bytes gave the error %v", n, err)
+		}
+	}
+}
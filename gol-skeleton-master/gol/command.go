@@ -0,0 +1,160 @@
+package gol
+
+import (
+	"context"
+	"errors"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// Command represents any request to control a run, sent on the controls channel given to RunContext.
+// Every command has a Reply channel the run answers on once it has carried the command out, with nil,
+// or with the error if it could not. Reply may be nil if the answer is not wanted, and otherwise
+// should be buffered or read promptly, as the run waits for its answer to be taken.
+type Command interface {
+	reply(err error)
+}
+
+// Reply is the channel a command is answered on.
+type Reply chan<- error
+
+func (r Reply) reply(err error) {
+	if r != nil {
+		r <- err
+	}
+}
+
+// The errors a command is answered with when the run is not in a state to carry it out.
+var (
+	ErrNotPaused    = errors.New("the run is not paused")
+	ErrNoTurnsLeft  = errors.New("the run has no turns left")
+	ErrNotInHistory = errors.New("the turn is not in the history")
+	ErrDistributed  = errors.New("the board of a distributed run cannot be changed")
+	ErrInterrupted  = errors.New("the run was resumed or ended before the steps were taken")
+)
+
+// `Save` is a Command to save the board, which is the board in the window if it has been stepped back while paused.
+// With no Path it is saved as 's' does, as an image with a snapshot next to it and as a checkpoint,
+// and the run stops with a RunError if they cannot be written. With a Path it is saved there alone,
+// in the format its extension names: .pgm, .png, .ckpt or one of the pattern formats.
+type Save struct {
+	Path string
+	Reply
+}
+
+// `Pause` is a Command to pause the run, as 'p' does while it is running. Pausing a paused run does nothing.
+type Pause struct {
+	Reply
+}
+
+// `Resume` is a Command to carry on with a paused run, as 'p' does while it is paused,
+// bringing the window back to the current turn. Resuming a running run does nothing.
+type Resume struct {
+	Reply
+}
+
+// `Step` is a Command to move a paused run forward Turns turns, or 1 if Turns is not positive, as 'n' does.
+// The window steps forward through the history first, and new turns are computed once it is back at the current one.
+// The turns are taken one at a time at the capped speed, with other commands carried out in between, and a second
+// Step adds its turns to those in hand. It is answered once they have all been taken, or with ErrInterrupted if the
+// run is resumed or ends first.
+type Step struct {
+	Turns int
+	Reply
+}
+
+// `Rewind` is a Command to step the window of a paused run back Turns turns through the history,
+// or forward if Turns is negative, as ',' and '.' do.
+type Rewind struct {
+	Turns int
+	Reply
+}
+
+// `Quit` is a Command to save the board and end the run, as 'q' does. With Shutdown set a distributed run
+// is ended along with the broker and its workers, as 'k' does, rather than left on the broker. It is answered
+// once the board has been saved and the events channel closed, with the error that ended the run if there was one.
+type Quit struct {
+	Shutdown bool
+	Reply
+}
+
+// `SetSpeed` is a Command to cap the turns at TurnsPerSecond, or to lift the cap if it is 0.
+type SetSpeed struct {
+	TurnsPerSecond float64
+	Reply
+}
+
+// `ChangeSpeed` is a Command to raise the cap on the turns per second by Steps steps, or to lower it
+// if Steps is negative, as '+' and '-' do.
+type ChangeSpeed struct {
+	Steps int
+	Reply
+}
+
+// `LoadPattern` is a Command to read the pattern file at Path and copy it onto the board with the top left
+// corner of its bounding box at (X, Y), replacing the cells under the box. The history is dropped,
+// as it no longer leads to the board.
+type LoadPattern struct {
+	Path string
+	X, Y int
+	Reply
+}
+
+// `SetCell` is a Command to make Cell alive or dead. The history is dropped, as it no longer leads to the board.
+type SetCell struct {
+	Cell  util.Cell
+	Alive bool
+	Reply
+}
+
+// KeyCommands returns the commands for the keys pressed on keyPresses, as the SDL window sends them,
+// until ctx is done: 's' saves, 'p' pauses and resumes, 'q' and 'k' quit, 'n' steps, ',' and '.'
+// rewind and '+' and '-' change the speed. The commands are sent without a Reply.
+func KeyCommands(ctx context.Context, keyPresses <-chan rune) <-chan Command {
+	commands := make(chan Command)
+	go func() {
+		paused := false
+		for {
+			var command Command
+			select {
+			case key := <-keyPresses:
+				switch key {
+				case 's':
+					command = Save{}
+				case 'q':
+					command = Quit{}
+				case 'k':
+					command = Quit{Shutdown: true}
+				case 'n':
+					command = Step{}
+				case ',':
+					command = Rewind{Turns: 1}
+				case '.':
+					command = Rewind{Turns: -1}
+				case '+', '=':
+					command = ChangeSpeed{Steps: 1}
+				case '-':
+					command = ChangeSpeed{Steps: -1}
+				case 'p':
+					if paused {
+						command = Resume{}
+					} else {
+						command = Pause{}
+					}
+					paused = !paused
+				default:
+					continue
+				}
+			case <-ctx.Done():
+				return
+			}
+
+			select {
+			case commands <- command:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return commands
+}
@@ -1,6 +1,8 @@
 package gol
 
 import (
+	"context"
+	"fmt"
 	"strconv"
 	"sync"
 	"time"
@@ -9,104 +11,282 @@ import (
 )
 
 type distributorChannels struct {
-	events     chan<- Event
-	ioCommand  chan<- ioCommand
-	ioIdle     <-chan bool
-	ioFilename chan<- string
-	ioOutput   chan<- uint8
-	ioInput    <-chan uint8
+	events       chan<- Event
+	ioCommand    chan<- ioCommand
+	ioIdle       <-chan bool
+	ioFilename   chan<- string
+	ioOutput     chan<- uint8
+	ioInput      <-chan uint8
+	ioCheckpoint chan<- checkpoint
+	ioTurn       <-chan int
+	ioError      <-chan error
+	ioPattern    <-chan *pattern
 }
 
-const Save int = 0
-const Quit int = 1
-const Pause int = 2
-const unPause int = 3
+// engine computes turns for the distributor, either on the local worker pool or on a broker.
+// step returns the board after world together with the cells that flipped.
+// detach leaves the run going without the controller where the engine allows it, and
+// shutdown stops the engine like stop and also ends every process it runs on.
+type engine interface {
+	step(world *board) (*board, []util.Cell, error)
+	stop() error
+	detach() error
+	shutdown() error
+}
 
-func handleOutput(p Params, c distributorChannels, world [][]uint8, t int) {
+// handleOutput converts the packed board back to 255/0 bytes for the IO goroutine.
+func handleOutput(p Params, c distributorChannels, world *board, t int) error {
 	c.ioCommand <- ioOutput
 	outFilename := strconv.Itoa(p.ImageHeight) + "x" + strconv.Itoa(p.ImageWidth) + "x" + strconv.Itoa(t)
 	c.ioFilename <- outFilename
-	for y := 0; y < p.ImageHeight; y++ {
-		for x := 0; x < p.ImageWidth; x++ {
-			c.ioOutput <- world[y][x]
+	for _, row := range world.toBytes() {
+		for _, cell := range row {
+			c.ioOutput <- cell
 		}
 	}
 
 	// Wait for IO to finish
-	c.ioCommand <- ioCheckIdle
-	<-c.ioIdle
+	if err := <-c.ioError; err != nil {
+		return err
+	}
 	c.events <- ImageOutputComplete{
 		CompletedTurns: t,
 		Filename:       outFilename,
 	}
+	return nil
+}
+
+// handleCheckpoint hands the IO goroutine a checkpoint of the board after turn t to save.
+func handleCheckpoint(p Params, c distributorChannels, world *board, t int) error {
+	c.ioCommand <- ioCheckpoint
+	c.ioCheckpoint <- checkpoint{turn: t, rule: p.Rule, boundary: p.Boundary, world: world.toBytes()}
+	return <-c.ioError
+}
+
+// handleSave saves the board after turn t as an image and as a checkpoint, as 's' asks for.
+func handleSave(p Params, c distributorChannels, world *board, t int) error {
+	if err := handleOutput(p, c, world, t); err != nil {
+		return err
+	}
+	return handleCheckpoint(p, c, world, t)
+}
+
+// handleExport saves the board after turn t to path, in the format its extension names.
+func handleExport(p Params, c distributorChannels, world *board, t int, path string) error {
+	c.ioCommand <- ioExport
+	c.ioFilename <- path
+	c.ioCheckpoint <- checkpoint{turn: t, rule: p.Rule, boundary: p.Boundary, world: world.toBytes()}
+	if err := <-c.ioError; err != nil {
+		return err
+	}
+	c.events <- ImageOutputComplete{CompletedTurns: t, Filename: path}
+	return nil
+}
+
+// handlePattern has the IO goroutine read the pattern file at path.
+func handlePattern(c distributorChannels, path string) (*pattern, error) {
+	c.ioCommand <- ioPattern
+	c.ioFilename <- path
+	if err := <-c.ioError; err != nil {
+		return nil, err
+	}
+	return <-c.ioPattern, nil
+}
+
+// handleError reports err as the reason the run stopped after turn t and closes the events channel.
+func handleError(c distributorChannels, t int, err error) error {
+	c.events <- RunError{CompletedTurns: t, Err: err}
+	close(c.events)
+	return err
 }
 
-func handleInput(p Params, c distributorChannels, world [][]uint8) [][]uint8 {
-	filename := strconv.Itoa(p.ImageHeight) + "x" + strconv.Itoa(p.ImageWidth)
+// handleInput reads the 255/0 bytes from the IO goroutine into a packed board.
+// It returns the board with the number of turns it has been through, which is 0 unless it is a checkpoint.
+func handleInput(p Params, c distributorChannels) (*board, int, error) {
+	world := make([][]uint8, p.ImageHeight)
+	for i := range world {
+		world[i] = make([]uint8, p.ImageWidth)
+	}
+
+	filename := p.inputPath()
 	c.ioCommand <- ioInput
 	c.ioFilename <- filename
+	if err := <-c.ioError; err != nil {
+		return nil, 0, err
+	}
 	for y := 0; y < p.ImageHeight; y++ {
 		for x := 0; x < p.ImageWidth; x++ {
-			num := <-c.ioInput
-			world[y][x] = num
-			if num == 255 {
-				c.events <- CellFlipped{
-					CompletedTurns: 0,
-					Cell:           util.Cell{X: x, Y: y},
-				}
-			}
+			world[y][x] = <-c.ioInput
 		}
 	}
-	return world
+	turn := <-c.ioTurn
+
+	packed := newBoard(p.ImageWidth, p.ImageHeight, p.Boundary)
+	packed.fromBytes(world)
+	handleFlips(p, c, turn, packed.aliveCells())
+	return packed, turn, nil
 }
 
-func handleKeyPress(p Params, c distributorChannels, keyPresses <-chan rune, action chan int) {
-	paused := false
-	for {
-		input := <-keyPresses
-		switch input {
-		case 's':
-			action <- Save
-		case 'q':
-			action <- Quit
-			return
-		case 'p':
-			if paused {
-				action <- unPause
-				paused = false
-			} else {
-				action <- Pause
-				paused = true
-			}
+// handleFlips sends flipped, the cells that flipped in the turn after turn t in order of row and then column,
+// in an event for each strip of rows a worker computes. flipped is sent as it is, so it must not be reused.
+func handleFlips(p Params, c distributorChannels, t int, flipped []util.Cell) {
+	var buffer flipBuffer
+	buffer.send(p, c, splitRows(p.ImageHeight, p.Threads), t, flipped)
+}
+
+// sendFlips sends cells, which flipped in the turn after turn t, in a CellsFlipped event, or as runs
+// along their rows in a RowsFlipped event if p.FlipRuns is set. cells must be in order of row and then column.
+func sendFlips(p Params, c distributorChannels, t int, cells []util.Cell) {
+	if !p.FlipRuns {
+		c.events <- CellsFlipped{CompletedTurns: t, Cells: cells}
+		return
+	}
+	c.events <- RowsFlipped{CompletedTurns: t, Runs: appendRuns(nil, cells)}
+}
+
+// appendRuns appends cells, in order of row and then column, to runs as runs along their rows.
+func appendRuns(runs []FlipRun, cells []util.Cell) []FlipRun {
+	start := len(runs)
+	for _, cell := range cells {
+		last := len(runs) - 1
+		if last >= start && runs[last].Y == cell.Y && runs[last].X+runs[last].Length == cell.X {
+			runs[last].Length++
+		} else {
+			runs = append(runs, FlipRun{X: cell.X, Y: cell.Y, Length: 1})
 		}
 	}
+	return runs
 }
 
-func distributor(p Params, c distributorChannels, keyPresses <-chan rune) {
-	world := make([][]uint8, p.ImageHeight)
-	prevWorld := make([][]uint8, p.ImageHeight)
-	for i := range world {
-		world[i] = make([]uint8, p.ImageWidth)
-		prevWorld[i] = make([]uint8, p.ImageWidth)
+// handleRewind moves the window from the board after turn view to the board after turn to,
+// sending the cells recorded in h as flipping in each turn in between. Both turns must be held by h.
+func handleRewind(p Params, c distributorChannels, h *history, view, to int) {
+	for ; view > to; view-- {
+		sendFlips(p, c, view-1, h.delta(view-1))
+		c.events <- TurnComplete{CompletedTurns: view - 1}
 	}
+	for ; view < to; view++ {
+		sendFlips(p, c, view, h.delta(view))
+		c.events <- TurnComplete{CompletedTurns: view + 1}
+	}
+}
 
-	world = handleInput(p, c, world)
+// attachRemote follows the run in progress on the broker at p.Broker, or starts one with the input image.
+// It returns the run's parameters with the board and turn to carry on from.
+func attachRemote(ctx context.Context, p Params, c distributorChannels) (*remoteEngine, Params, *board, int, error) {
+	remote, err := dialBroker(ctx, p, c.events)
+	if err != nil {
+		return nil, p, nil, 0, err
+	}
+	run, world, turn, ok, err := remote.attach()
+	if err == nil && !ok {
+		world, turn, err = handleInput(p, c)
+		if err == nil {
+			err = remote.start(p, world, turn)
+		}
+		if err != nil {
+			remote.disconnect()
+			return nil, p, nil, 0, err
+		}
+		return remote, p, world, turn, nil
+	}
+	if err == nil && (run.ImageWidth != p.ImageWidth || run.ImageHeight != p.ImageHeight) {
+		err = &BrokerError{p.Broker, fmt.Errorf("the run on the broker is %vx%v, not %vx%v",
+			run.ImageWidth, run.ImageHeight, p.ImageWidth, p.ImageHeight)}
+	}
+	if err != nil {
+		remote.disconnect()
+		return nil, p, nil, 0, err
+	}
 
+	// Draw the board the controller joined on, as if it had just been loaded.
+	run.Broker = p.Broker
+	run.Threads = p.Threads
+	run.FlipRuns = p.FlipRuns
+	handleFlips(run, c, turn, world.aliveCells())
+	return remote, run, world, turn, nil
+}
+
+// distributor runs the turns and returns the error the run stopped with, if any.
+// When it returns every goroutine it started has stopped or been told to, the IO goroutine included.
+func distributor(ctx context.Context, p Params, c distributorChannels, controls <-chan Command) error {
+	defer close(c.ioCommand)
+
+	var engine engine
+	var world *board
 	turn := 0
+	var err error
+	if p.Broker != "" {
+		var remote *remoteEngine
+		if remote, p, world, turn, err = attachRemote(ctx, p, c); err == nil {
+			engine = remote
+		}
+	} else if world, turn, err = handleInput(p, c); err == nil {
+		engine = newWorkerPool(p)
+	}
+	if err != nil {
+		return handleError(c, turn, err)
+	}
+
 	ticker := time.NewTicker(2 * time.Second)
+	var checkpoints <-chan time.Time
+	if p.CheckpointInterval > 0 {
+		checkpointTicker := time.NewTicker(p.CheckpointInterval)
+		defer checkpointTicker.Stop()
+		checkpoints = checkpointTicker.C
+	}
 	done := make(chan bool)
+	// While paused the window can be stepped back through the history to show the board after turn view.
+	// The turns are recorded from the start with p.Rewind, and otherwise from the first pause.
+	var history history
+	recording := p.Rewind
+	// The cells flipped in each turn are sent in buffers that are reused once the receiver is done with them.
+	var flips flipBuffers
+	view := turn
+	// The limiter holds the turns back to p.TurnsPerSecond without holding up the key presses.
+	var limiter limiter
+	limiter.set(p.TurnsPerSecond)
+	defer limiter.stop()
 	pause := false
 	quit := false
+	kill := false
 	finished := false
+	// failed is the error that stopped the run, if one did.
+	var failed error
+	fail := func(err error) {
+		if err != nil {
+			failed = err
+			finished = true
+		}
+	}
 
-	var mu sync.Mutex
+	// ending is the Quit that ended the run, answered once the board is saved and the events channel closed.
+	var ending Command
+	answer := func(err error) error {
+		if ending != nil {
+			ending.reply(err)
+		}
+		return err
+	}
 
-	action := make(chan int)
+	// steps is the number of turns the Steps in stepping have still to take, one a pass of the loop
+	// so that other commands and the end of the run are not held up behind them.
+	steps := 0
+	var stepping []Command
+	endSteps := func(err error) {
+		for _, command := range stepping {
+			command.reply(err)
+		}
+		steps, stepping = 0, nil
+	}
 
-	go handleKeyPress(p, c, keyPresses, action)
+	var mu sync.Mutex
 
 	// Send StateChange event indicating Executing state at the start
 	c.events <- StateChange{CompletedTurns: turn, NewState: Executing}
+	if limiter.tps > 0 {
+		c.events <- SpeedChanged{CompletedTurns: turn, TurnsPerSecond: limiter.tps}
+	}
 
 	// Start ticker for AliveCellsCount events
 	go func() {
@@ -115,15 +295,11 @@ func distributor(p Params, c distributorChannels, keyPresses <-chan rune) {
 			case <-done:
 				return
 			case <-ticker.C:
+				// The pool reuses boards, so count while the distributor cannot swap them.
 				mu.Lock()
-				snapshot := make([][]uint8, p.ImageHeight)
-				for i := range prevWorld {
-					snapshot[i] = make([]uint8, p.ImageWidth)
-					copy(snapshot[i], prevWorld[i])
-				}
+				aliveCount := world.aliveCount()
 				currentTurn := turn
 				mu.Unlock()
-				aliveCount, _ := calculateAliveCells(p, snapshot)
 				c.events <- AliveCellsCount{
 					CompletedTurns: currentTurn,
 					CellsCount:     aliveCount,
@@ -132,90 +308,241 @@ func distributor(p Params, c distributorChannels, keyPresses <-chan rune) {
 		}
 	}()
 
-	for !finished && (turn < p.Turns || pause) {
-		if pause {
-			select {
-			case command := <-action:
-				switch command {
-				case unPause:
-					pause = false
-					// Send StateChange event indicating Executing state
-					c.events <- StateChange{CompletedTurns: turn, NewState: Executing}
-				case Quit:
-					quit = true
-					finished = true
-				case Save:
-					mu.Lock()
-					snapshot := make([][]uint8, p.ImageHeight)
-					for i := range world {
-						snapshot[i] = make([]uint8, p.ImageWidth)
-						copy(snapshot[i], world[i])
-					}
-					currentTurn := turn
-					mu.Unlock()
-					handleOutput(p, c, snapshot, currentTurn)
-				}
-			default:
-				// Sleep briefly to prevent busy waiting
-				time.Sleep(100 * time.Millisecond)
+	// advance computes the next turn and sends its events.
+	advance := func() {
+		limiter.took()
+		next, flipFragment, err := engine.step(world)
+		if err != nil {
+			// A turn cut short by ctx is not a failure, as the loop quits the run for it next.
+			if ctx.Err() == nil {
+				fail(err)
 			}
-			continue
+			return
 		}
+		if recording {
+			history.record(world, turn, flipFragment)
+		}
+		flips.send(p, c, turn, flipFragment)
+		mu.Lock()
+		world = next
+		turn++
+		mu.Unlock()
+		c.events <- TurnComplete{CompletedTurns: turn}
+	}
+
+	// changeSpeed sets the cap on turns per second and reports it.
+	changeSpeed := func(tps float64) {
+		limiter.set(tps)
+		c.events <- SpeedChanged{CompletedTurns: turn, TurnsPerSecond: tps}
+	}
 
-		select {
-		case command := <-action:
-			switch command {
-			case Pause:
+	// shown returns the board in the window and its turn, which may be from before the pause.
+	shown := func() (*board, int) {
+		if pause && view != turn {
+			return history.boardAt(view), view
+		}
+		return world, turn
+	}
+
+	// edit makes the change to the board and sends the cells it flipped. The window is brought back to
+	// the current turn first, and the history is dropped afterwards as it no longer leads to the board.
+	edit := func(change func() ([]util.Cell, error)) error {
+		if _, remote := engine.(*remoteEngine); remote {
+			return ErrDistributed
+		}
+		handleRewind(p, c, &history, view, turn)
+		view = turn
+		mu.Lock()
+		flipped, err := change()
+		world.fillGhosts()
+		mu.Unlock()
+		if err != nil || len(flipped) == 0 {
+			return err
+		}
+		history.clear()
+		sendFlips(p, c, turn, flipped)
+		c.events <- TurnComplete{CompletedTurns: turn}
+		return nil
+	}
+
+	// execute carries out command and returns the error to answer it with.
+	execute := func(command Command) error {
+		switch command := command.(type) {
+		case Pause:
+			if !pause {
 				pause = true
+				recording = true
+				view = turn
 				// Send StateChange event indicating Paused state
 				c.events <- StateChange{CompletedTurns: turn, NewState: Paused}
-			case Quit:
-				quit = true
-				finished = true
-			case Save:
-				mu.Lock()
-				snapshot := make([][]uint8, p.ImageHeight)
-				for i := range world {
-					snapshot[i] = make([]uint8, p.ImageWidth)
-					copy(snapshot[i], world[i])
+			}
+		case Resume:
+			if pause {
+				pause = false
+				endSteps(ErrInterrupted)
+				handleRewind(p, c, &history, view, turn)
+				view = turn
+				// Send StateChange event indicating Executing state
+				c.events <- StateChange{CompletedTurns: turn, NewState: Executing}
+			}
+		case Step:
+			if !pause {
+				return ErrNotPaused
+			}
+			if command.Turns > 1 {
+				steps += command.Turns
+			} else {
+				steps++
+			}
+		case Rewind:
+			if !pause {
+				return ErrNotPaused
+			}
+			to := view - command.Turns
+			if to != view && (to > turn || !history.holds(to)) {
+				return ErrNotInHistory
+			}
+			handleRewind(p, c, &history, view, to)
+			view = to
+		case Quit:
+			quit = true
+			kill = command.Shutdown
+			finished = true
+		case Save:
+			saved, t := shown()
+			if command.Path != "" {
+				return handleExport(p, c, saved, t, command.Path)
+			}
+			fail(handleSave(p, c, saved, t))
+			return failed
+		case SetSpeed:
+			if command.TurnsPerSecond < 0 {
+				return fmt.Errorf("%v turns per second is negative", command.TurnsPerSecond)
+			}
+			changeSpeed(command.TurnsPerSecond)
+		case ChangeSpeed:
+			tps := limiter.tps
+			for i := 0; i < command.Steps; i++ {
+				tps = faster(tps)
+			}
+			for i := 0; i > command.Steps; i-- {
+				tps = slower(tps)
+			}
+			changeSpeed(tps)
+		case SetCell:
+			x, y := command.Cell.X, command.Cell.Y
+			if x < 0 || y < 0 || x >= p.ImageWidth || y >= p.ImageHeight {
+				return fmt.Errorf("the cell (%v, %v) is not on the %vx%v board", x, y, p.ImageWidth, p.ImageHeight)
+			}
+			return edit(func() ([]util.Cell, error) {
+				if world.get(x, y) == command.Alive {
+					return nil, nil
 				}
-				currentTurn := turn
-				mu.Unlock()
-				handleOutput(p, c, snapshot, currentTurn)
+				world.set(x, y, command.Alive)
+				return []util.Cell{command.Cell}, nil
+			})
+		case LoadPattern:
+			pattern, err := handlePattern(c, command.Path)
+			if err != nil {
+				return err
 			}
+			return edit(func() ([]util.Cell, error) {
+				return pattern.paste(world, command.X, command.Y)
+			})
 		default:
-			if !quit && turn < p.Turns {
-				mu.Lock()
-				for i := range world {
-					copy(prevWorld[i], world[i])
-				}
-				mu.Unlock()
-				var flipFragment []util.Cell
-				world, flipFragment = calculateNextState(p.ImageHeight, p.ImageWidth, 0, p.ImageHeight, prevWorld)
-				for _, cell := range flipFragment {
-					c.events <- CellFlipped{
-						CompletedTurns: turn,
-						Cell:           cell,
-					}
+			return fmt.Errorf("unknown command %T", command)
+		}
+		return nil
+	}
+
+	// step takes the next turn of the Steps in hand: forward through the history first,
+	// and a new turn once the window is back at the current one.
+	step := func() {
+		switch {
+		case view < turn:
+			limiter.took()
+			handleRewind(p, c, &history, view, view+1)
+			view++
+		case turn >= p.Turns:
+			endSteps(ErrNoTurnsLeft)
+			return
+		default:
+			advance()
+			view = turn
+			if failed != nil {
+				endSteps(failed)
+				return
+			}
+			c.events <- AliveCellsCount{CompletedTurns: turn, CellsCount: world.aliveCount()}
+		}
+		if steps--; steps == 0 {
+			endSteps(nil)
+		}
+	}
+
+	for !finished && (turn < p.Turns || pause) {
+		var command Command
+		if pause && steps == 0 {
+			// Nothing changes while paused, so wait for the next command.
+			select {
+			case command = <-controls:
+			case <-ctx.Done():
+				command = Quit{}
+			}
+		} else {
+			select {
+			case command = <-controls:
+			case <-ctx.Done():
+				command = Quit{}
+			case <-checkpoints:
+				fail(handleCheckpoint(p, c, world, turn))
+				continue
+			case <-limiter.ready():
+				if pause {
+					step()
+				} else {
+					advance()
 				}
-				mu.Lock()
-				turn++
-				mu.Unlock()
-				c.events <- TurnComplete{CompletedTurns: turn}
-			} else if quit {
-				break
-			} else if turn >= p.Turns {
-				break
+				continue
 			}
 		}
+		err := execute(command)
+		switch command.(type) {
+		case Quit:
+			ending = command
+			continue
+		case Step:
+			if err == nil {
+				stepping = append(stepping, command)
+				continue
+			}
+		}
+		command.reply(err)
 	}
+	endSteps(ErrInterrupted)
 
 	ticker.Stop()
 	done <- true
+	if failed != nil {
+		// The run is over either way, so an error ending it is not worth reporting over the first.
+		engine.stop()
+		return answer(handleError(c, turn, failed))
+	}
+	if kill {
+		err = engine.shutdown()
+	} else if quit {
+		err = engine.detach()
+	} else {
+		err = engine.stop()
+	}
+	if err == nil {
+		err = handleOutput(p, c, world, turn)
+	}
+	if err != nil {
+		return answer(handleError(c, turn, err))
+	}
 
-	_, aliveCells := calculateAliveCells(p, world)
-
-	handleOutput(p, c, world, turn)
+	aliveCells := world.aliveCells()
 
 	if quit {
 		c.events <- StateChange{CompletedTurns: turn, NewState: Quitting}
@@ -229,4 +556,5 @@ func distributor(p Params, c distributorChannels, keyPresses <-chan rune) {
 	<-c.ioIdle
 
 	close(c.events)
+	return answer(nil)
 }
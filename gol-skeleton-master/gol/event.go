@@ -58,11 +58,26 @@ type CellFlipped struct { // implements Event
 // You can send many times of `CellsFlipped` event in a turn, i.e., each worker could send `CellsFlipped`.
 // **Please be careful not to send `CellFlipped` and `CellsFlipped` at the same time, as they may conflict.**
 // Choose one of them.
+// A run reuses Cells once the TurnComplete after the one that follows it has been received, so copy it to keep it longer.
 type CellsFlipped struct { // implements Event
 	CompletedTurns int
 	Cells          []util.Cell
 }
 
+// `RowsFlipped` is an Event notifying the GUI about a change of state of many cells, as runs of cells along their rows.
+// It is sent in place of `CellsFlipped` when Params.FlipRuns is set, and is more compact where the flipped cells
+// lie next to each other. The runs are in order of row and then column, and Cells lists the cells in them.
+// Runs is reused as the Cells of a `CellsFlipped` is.
+type RowsFlipped struct { // implements Event
+	CompletedTurns int
+	Runs           []FlipRun
+}
+
+// FlipRun is the Length cells along row Y starting from column X.
+type FlipRun struct {
+	X, Y, Length int
+}
+
 // `TurnComplete` is an Event notifying the GUI about turn completion.
 // SDL will render a frame when this event is sent.
 // All `CellFlipped` or `CellsFlipped` events must be sent *before* `TurnComplete`.
@@ -78,6 +93,51 @@ type FinalTurnComplete struct {
 	Alive          []util.Cell
 }
 
+// `WorkerLost` is an Event notifying the user that the broker has given up on a worker in a distributed run.
+// The worker's share of the board is computed by the workers that are left, or by the broker if there are none.
+type WorkerLost struct { // implements Event
+	CompletedTurns int
+	Address        string
+}
+
+// `WorkerJoined` is an Event notifying the user that a worker has registered with the broker during a distributed run.
+type WorkerJoined struct { // implements Event
+	CompletedTurns int
+	Address        string
+}
+
+// `MembershipChanged` is an Event notifying the user that the broker has shared the rows of the board out
+// again among its workers in a distributed run, because workers joined or left or their speeds changed.
+// Strips is empty while the broker has no workers and computes the turns itself.
+type MembershipChanged struct { // implements Event
+	CompletedTurns int
+	Strips         []WorkerStrip
+}
+
+// `SpeedChanged` is an Event notifying the user of the rate the turns are capped at.
+// It is sent at the start of a capped run and whenever the cap is changed with '+' or '-'.
+// A TurnsPerSecond of 0 means the turns run as fast as they can.
+type SpeedChanged struct { // implements Event
+	CompletedTurns int
+	TurnsPerSecond float64
+}
+
+// `RunError` is an Event notifying the user that the run has stopped because of an error.
+// Err is a *PgmError, *SizeError or checkpoint or pattern error for an input that cannot be read,
+// wrapping fs.ErrNotExist if it is missing, an *OutputError for an image or checkpoint that cannot
+// be written, or a *BrokerError for a broker that cannot be reached. It is the last event before
+// the events channel is closed, and Run returns the same error.
+type RunError struct { // implements Event
+	CompletedTurns int
+	Err            error
+}
+
+// WorkerStrip is the rows [StartY, EndY) of the board computed by the worker at Address.
+type WorkerStrip struct {
+	Address      string
+	StartY, EndY int
+}
+
 // String methods allow the different types of Events and States to be printed.
 
 func (state State) String() string {
@@ -133,6 +193,25 @@ func (event CellsFlipped) GetCompletedTurns() int {
 	return event.CompletedTurns
 }
 
+func (event RowsFlipped) String() string {
+	return ""
+}
+
+func (event RowsFlipped) GetCompletedTurns() int {
+	return event.CompletedTurns
+}
+
+// Cells returns the cells in the runs.
+func (event RowsFlipped) Cells() []util.Cell {
+	var cells []util.Cell
+	for _, run := range event.Runs {
+		for x := run.X; x < run.X+run.Length; x++ {
+			cells = append(cells, util.Cell{X: x, Y: run.Y})
+		}
+	}
+	return cells
+}
+
 func (event TurnComplete) String() string {
 	return ""
 }
@@ -149,6 +228,53 @@ func (event FinalTurnComplete) GetCompletedTurns() int {
 	return event.CompletedTurns
 }
 
+func (event WorkerLost) String() string {
+	return fmt.Sprintf("Worker %v lost", event.Address)
+}
+
+func (event WorkerLost) GetCompletedTurns() int {
+	return event.CompletedTurns
+}
+
+func (event WorkerJoined) String() string {
+	return fmt.Sprintf("Worker %v joined", event.Address)
+}
+
+func (event WorkerJoined) GetCompletedTurns() int {
+	return event.CompletedTurns
+}
+
+func (event MembershipChanged) String() string {
+	shares := make([]string, len(event.Strips))
+	for i, strip := range event.Strips {
+		shares[i] = fmt.Sprintf("%v:%v", strip.Address, strip.EndY-strip.StartY)
+	}
+	return fmt.Sprintf("Workers %v", shares)
+}
+
+func (event MembershipChanged) GetCompletedTurns() int {
+	return event.CompletedTurns
+}
+
+func (event SpeedChanged) String() string {
+	if event.TurnsPerSecond == 0 {
+		return "Speed unlimited"
+	}
+	return fmt.Sprintf("Speed %v turns/sec", event.TurnsPerSecond)
+}
+
+func (event SpeedChanged) GetCompletedTurns() int {
+	return event.CompletedTurns
+}
+
+func (event RunError) String() string {
+	return fmt.Sprintf("Error: %v", event.Err)
+}
+
+func (event RunError) GetCompletedTurns() int {
+	return event.CompletedTurns
+}
+
 // This might all seem like weird syntax to you...
 // You have however seen something similar to it before in first year.
 
@@ -0,0 +1,160 @@
+package gol
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// gifDelay is the time each frame of a recording is shown for, in hundredths of a second.
+const gifDelay = 10
+
+// cellPalette draws dead cells black and alive cells white, as the board is shown on screen.
+var cellPalette = color.Palette{color.Black, color.White}
+
+// cellImage draws a width by height board, where alive reports whether a cell is alive,
+// with each cell as a scale by scale square.
+func cellImage(width, height, scale int, alive func(x, y int) bool) *image.Paletted {
+	img := image.NewPaletted(image.Rect(0, 0, width*scale, height*scale), cellPalette)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if !alive(x, y) {
+				continue
+			}
+			for dy := 0; dy < scale; dy++ {
+				row := img.Pix[(y*scale+dy)*img.Stride:]
+				for dx := 0; dx < scale; dx++ {
+					row[x*scale+dx] = 1
+				}
+			}
+		}
+	}
+	return img
+}
+
+// writePngFile writes world, a grid of 255/0 bytes, to path as a png image.
+func writePngFile(path string, world [][]byte, scale int) error {
+	width := 0
+	if len(world) > 0 {
+		width = len(world[0])
+	}
+	img := cellImage(width, len(world), scale, func(x, y int) bool { return world[y][x] == 255 })
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(file, img); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// GIFRecorder records a run as an animated gif at Path. It keeps its own copy of the board from
+// the CellFlipped, CellsFlipped and RowsFlipped events and takes a frame of it on every TurnComplete for turn
+// Start, Start+Every, Start+2*Every and so on up to End, as well as of the board the run starts from
+// if that is turn Start. Every defaults to 1, and an End of 0 records up to the last turn. Each frame is
+// written to the file as it is taken, and a turn no later than the last one recorded, as when a paused run
+// is stepped back, is skipped so the animation only goes forward.
+type GIFRecorder struct {
+	Path       string
+	Every      int
+	Start, End int
+
+	width, height int
+	scale         int
+	world         [][]bool
+	started       bool
+	out           *gifWriter
+	last          int
+	err           error
+}
+
+// Record passes every event from events on to forward while recording the board of the run with
+// parameters p. Once events is closed it writes the gif, then closes forward and returns any error
+// from writing the gif, so forward can be drained to wait for the recording to be saved.
+// forward may be nil if the events are read elsewhere, as when events is a subscription to a Bus.
+func (r *GIFRecorder) Record(p Params, events <-chan Event, forward chan<- Event) error {
+	if forward != nil {
+		defer close(forward)
+	}
+	r.width, r.height, r.scale = p.ImageWidth, p.ImageHeight, p.scale()
+	r.world = make([][]bool, r.height)
+	for y := range r.world {
+		r.world[y] = make([]bool, r.width)
+	}
+
+	for event := range events {
+		switch e := event.(type) {
+		case CellFlipped:
+			r.flip(e.Cell)
+		case CellsFlipped:
+			for _, cell := range e.Cells {
+				r.flip(cell)
+			}
+		case RowsFlipped:
+			for _, cell := range e.Cells() {
+				r.flip(cell)
+			}
+		case StateChange:
+			// The first state change comes once the starting board has been sent.
+			if !r.started {
+				r.started = true
+				r.frame(e.CompletedTurns)
+			}
+		case TurnComplete:
+			r.started = true
+			r.frame(e.CompletedTurns)
+		}
+		if forward != nil {
+			// The event is passed on once it has been taken from the run, so the cells it holds are copied.
+			forward <- owned(event)
+		}
+	}
+	return r.save()
+}
+
+func (r *GIFRecorder) flip(cell util.Cell) {
+	r.world[cell.Y][cell.X] = !r.world[cell.Y][cell.X]
+}
+
+// frame takes a frame of the board after turn if the recording asks for one, creating the file for the first.
+// Once writing fails the recording stops, and save returns the error.
+func (r *GIFRecorder) frame(turn int) {
+	every := r.Every
+	if every < 1 {
+		every = 1
+	}
+	if turn < r.Start || (r.End > 0 && turn > r.End) || (turn-r.Start)%every != 0 {
+		return
+	}
+	if r.err != nil || (r.out != nil && turn <= r.last) {
+		return
+	}
+	if r.out == nil {
+		if r.out, r.err = createGIF(r.Path, r.width*r.scale, r.height*r.scale); r.err != nil {
+			return
+		}
+	}
+	r.last = turn
+	r.err = r.out.frame(cellImage(r.width, r.height, r.scale, func(x, y int) bool { return r.world[y][x] }))
+}
+
+// save finishes the gif at r.Path, returning the first error from writing it.
+func (r *GIFRecorder) save() error {
+	if r.out == nil {
+		if r.err != nil {
+			return r.err
+		}
+		return fmt.Errorf("%v: no turns between %v and %v to record", r.Path, r.Start, r.End)
+	}
+	if err := r.out.close(); r.err == nil {
+		r.err = err
+	}
+	return r.err
+}
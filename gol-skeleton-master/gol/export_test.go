@@ -0,0 +1,113 @@
+package gol
+
+import (
+	"fmt"
+	"image/gif"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+func TestWritePngFile(t *testing.T) {
+	world := randomWorld(5, 3, 1)
+	path := filepath.Join(t.TempDir(), "board.png")
+	if err := writePngFile(path, world, 3); err != nil {
+		t.Fatal(err)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	img, err := png.Decode(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size := img.Bounds().Size(); size.X != 15 || size.Y != 9 {
+		t.Fatalf("got a %vx%v image, expected 15x9", size.X, size.Y)
+	}
+	for y := 0; y < 9; y++ {
+		for x := 0; x < 15; x++ {
+			r, _, _, _ := img.At(x, y).RGBA()
+			if alive := world[y/3][x/3] == 255; alive != (r != 0) {
+				t.Fatalf("pixel (%v, %v) is %v for a cell alive %v", x, y, r, alive)
+			}
+		}
+	}
+}
+
+// TestGIFRecorder checks that the recorder passes every event on and takes frames
+// of the starting board and of the turns it was asked for, once each.
+func TestGIFRecorder(t *testing.T) {
+	recorder := &GIFRecorder{Path: filepath.Join(t.TempDir(), "run.gif"), Every: 2, Start: 0, End: 3}
+	events := make(chan Event)
+	forward := make(chan Event, 100)
+	errs := make(chan error, 1)
+	go func() { errs <- recorder.Record(Params{ImageWidth: 4, ImageHeight: 2, Scale: 2}, events, forward) }()
+
+	sent := []Event{
+		CellFlipped{CompletedTurns: 0, Cell: util.Cell{X: 0, Y: 0}},
+		StateChange{CompletedTurns: 0, NewState: Executing},
+		CellsFlipped{CompletedTurns: 0, Cells: []util.Cell{{X: 1, Y: 0}}},
+		TurnComplete{CompletedTurns: 1},
+		CellFlipped{CompletedTurns: 1, Cell: util.Cell{X: 0, Y: 0}},
+		CellFlipped{CompletedTurns: 1, Cell: util.Cell{X: 3, Y: 1}},
+		TurnComplete{CompletedTurns: 2},
+		CellFlipped{CompletedTurns: 2, Cell: util.Cell{X: 2, Y: 1}},
+		TurnComplete{CompletedTurns: 3},
+		CellFlipped{CompletedTurns: 3, Cell: util.Cell{X: 2, Y: 0}},
+		TurnComplete{CompletedTurns: 4},
+		// Stepping back to turn 2 while paused is not recorded again.
+		CellFlipped{CompletedTurns: 4, Cell: util.Cell{X: 2, Y: 0}},
+		CellFlipped{CompletedTurns: 4, Cell: util.Cell{X: 2, Y: 1}},
+		TurnComplete{CompletedTurns: 2},
+	}
+	for _, event := range sent {
+		events <- event
+	}
+	close(events)
+	if err := <-errs; err != nil {
+		t.Fatal(err)
+	}
+	received := 0
+	for range forward {
+		received++
+	}
+	if received != len(sent) {
+		t.Fatalf("%v events passed on, expected %v", received, len(sent))
+	}
+
+	file, err := os.Open(recorder.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	animation, err := gif.DecodeAll(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Turns 0 and 2 are recorded.
+	expected := [][]util.Cell{{{X: 0, Y: 0}}, {{X: 1, Y: 0}, {X: 3, Y: 1}}}
+	if len(animation.Image) != len(expected) {
+		t.Fatalf("got %v frames, expected %v", len(animation.Image), len(expected))
+	}
+	for i, img := range animation.Image {
+		if size := img.Bounds().Size(); size.X != 8 || size.Y != 4 {
+			t.Fatalf("frame %v is %vx%v, expected 8x4", i, size.X, size.Y)
+		}
+		var alive []util.Cell
+		for y := 0; y < 4; y += 2 {
+			for x := 0; x < 8; x += 2 {
+				if img.ColorIndexAt(x, y) == 1 {
+					alive = append(alive, util.Cell{X: x / 2, Y: y / 2})
+				}
+			}
+		}
+		if fmt.Sprint(alive) != fmt.Sprint(expected[i]) {
+			t.Errorf("frame %v has the cells %v alive, expected %v", i, alive, expected[i])
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package gol
+
+import (
+	"sort"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// flipBuffers sends the cells flipped in each turn of a run from buffers it reuses, so a running turn does
+// not allocate them. A buffer is reused once the receiver has taken the TurnComplete of the turn after its own,
+// as CellsFlipped and RowsFlipped allow, which is known once the events channel is empty two turns later.
+// While the receiver lags behind, new buffers are made instead.
+type flipBuffers struct {
+	strips []strip
+	// sent holds the buffers sent in the order they were sent, and free those ready to be reused.
+	sent, free []flipBuffer
+}
+
+// flipBuffer holds the cells flipped in a turn and the runs along their rows, with the number of turns
+// sent since.
+type flipBuffer struct {
+	cells []util.Cell
+	runs  []FlipRun
+	age   int
+}
+
+// send sends flipped, which the engine reuses, as handleFlips does from a buffer of its own.
+func (f *flipBuffers) send(p Params, c distributorChannels, t int, flipped []util.Cell) {
+	if f.strips == nil {
+		f.strips = splitRows(p.ImageHeight, p.Threads)
+	}
+	taken := len(c.events) == 0
+	for i := range f.sent {
+		f.sent[i].age++
+	}
+	for taken && len(f.sent) > 0 && f.sent[0].age >= 2 {
+		f.free = append(f.free, f.sent[0])
+		f.sent = f.sent[:copy(f.sent, f.sent[1:])]
+	}
+
+	var buffer flipBuffer
+	if last := len(f.free) - 1; last >= 0 {
+		buffer, f.free = f.free[last], f.free[:last]
+	}
+	buffer.age = 0
+	if !p.FlipRuns {
+		// The runs are made as the cells are sent, so only the cells themselves need copying.
+		buffer.cells = append(buffer.cells[:0], flipped...)
+		flipped = buffer.cells
+	}
+	buffer.send(p, c, f.strips, t, flipped)
+	f.sent = append(f.sent, buffer)
+}
+
+// send sends cells, which flipped in the turn after turn t in order of row and then column, in an event for
+// each strip that has any, as sendFlips does. The runs are made in the buffer, and the cells sent as they are.
+func (b *flipBuffer) send(p Params, c distributorChannels, strips []strip, t int, cells []util.Cell) {
+	b.runs = b.runs[:0]
+	for i, s := range strips {
+		end := len(cells)
+		if i < len(strips)-1 {
+			end = sort.Search(len(cells), func(j int) bool { return cells[j].Y >= s.endY })
+		}
+		if end == 0 {
+			continue
+		}
+		if p.FlipRuns {
+			start := len(b.runs)
+			b.runs = appendRuns(b.runs, cells[:end])
+			c.events <- RowsFlipped{CompletedTurns: t, Runs: b.runs[start:]}
+		} else {
+			c.events <- CellsFlipped{CompletedTurns: t, Cells: cells[:end]}
+		}
+		cells = cells[end:]
+	}
+}
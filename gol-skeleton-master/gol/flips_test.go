@@ -0,0 +1,76 @@
+package gol
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// TestHandleFlips checks that the flipped cells are sent in an event for each strip that has any,
+// as cells or as runs along their rows.
+func TestHandleFlips(t *testing.T) {
+	flipped := []util.Cell{{X: 0, Y: 0}, {X: 1, Y: 0}, {X: 2, Y: 0}, {X: 5, Y: 0}, {X: 7, Y: 1}, {X: 0, Y: 2}, {X: 3, Y: 7}, {X: 4, Y: 7}}
+	for _, test := range []struct {
+		runs     bool
+		expected []Event
+	}{
+		{false, []Event{
+			CellsFlipped{CompletedTurns: 3, Cells: flipped[:5]},
+			CellsFlipped{CompletedTurns: 3, Cells: flipped[5:6]},
+			CellsFlipped{CompletedTurns: 3, Cells: flipped[6:]},
+		}},
+		{true, []Event{
+			RowsFlipped{CompletedTurns: 3, Runs: []FlipRun{{0, 0, 3}, {5, 0, 1}, {7, 1, 1}}},
+			RowsFlipped{CompletedTurns: 3, Runs: []FlipRun{{0, 2, 1}}},
+			RowsFlipped{CompletedTurns: 3, Runs: []FlipRun{{3, 7, 2}}},
+		}},
+	} {
+		p := Params{ImageWidth: 8, ImageHeight: 8, Threads: 4, FlipRuns: test.runs}
+		events := make(chan Event, 8)
+		handleFlips(p, distributorChannels{events: events}, 3, flipped)
+		close(events)
+
+		var got []Event
+		for event := range events {
+			got = append(got, event)
+		}
+		if !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("with FlipRuns %v got %+v, expected %+v", test.runs, got, test.expected)
+		}
+	}
+}
+
+// TestTurnAllocs checks that once a run has warmed up its turns allocate nothing but the events they send,
+// which are boxed into the Event interface: one for each strip's flips and one for the TurnComplete.
+func TestTurnAllocs(t *testing.T) {
+	for _, threads := range []int{1, 4, 16} {
+		for _, runs := range []bool{false, true} {
+			p := Params{Threads: threads, ImageWidth: 512, ImageHeight: 512, Turns: 1 << 30, FlipRuns: runs,
+				Input: "../images/512x512.pgm", OutputDir: t.TempDir()}
+			ctx, cancel := context.WithCancel(context.Background())
+			events := make(chan Event)
+			go RunContext(ctx, p, events, nil)
+			turn := func() {
+				for event := range events {
+					if _, ok := event.(TurnComplete); ok {
+						return
+					}
+				}
+			}
+			for i := 0; i < 100; i++ {
+				turn()
+			}
+
+			expected := float64(len(splitRows(p.ImageHeight, p.Threads)) + 1)
+			if allocs := testing.AllocsPerRun(200, turn); allocs > expected {
+				t.Errorf("with %v threads and FlipRuns %v a turn made %v allocations, expected at most %v",
+					threads, runs, allocs, expected)
+			}
+			cancel()
+			for range events {
+			}
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package gol
+
+import (
+	"bufio"
+	"compress/lzw"
+	"encoding/binary"
+	"image"
+	"os"
+)
+
+// gifWriter writes an animated gif a frame at a time, so a long recording is never held in memory.
+// Every frame is drawn in cellPalette, which is written once as the global colour table, and the
+// animation loops forever.
+type gifWriter struct {
+	file *os.File
+	w    *bufio.Writer
+}
+
+// createGIF creates the file at path for a width by height animation and writes its header.
+func createGIF(path string, width, height int) (*gifWriter, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	g := &gifWriter{file: file, w: bufio.NewWriter(file)}
+	g.w.WriteString("GIF89a")
+	// The logical screen has a global colour table of 2 colours, the size field being log2(colours)-1.
+	g.uint16(width)
+	g.uint16(height)
+	g.w.Write([]byte{0x80, 0, 0})
+	for _, c := range cellPalette {
+		r, gr, b, _ := c.RGBA()
+		g.w.Write([]byte{byte(r >> 8), byte(gr >> 8), byte(b >> 8)})
+	}
+	// The NETSCAPE2.0 application extension with a loop count of 0 loops the animation forever.
+	g.w.Write([]byte{0x21, 0xff, 0x0b})
+	g.w.WriteString("NETSCAPE2.0")
+	g.w.Write([]byte{0x03, 0x01, 0, 0, 0})
+	return g, nil
+}
+
+func (g *gifWriter) uint16(v int) {
+	var b [2]byte
+	binary.LittleEndian.PutUint16(b[:], uint16(v))
+	g.w.Write(b[:])
+}
+
+// frame writes img, which must fill the animation and index cellPalette, as the next frame.
+func (g *gifWriter) frame(img *image.Paletted) error {
+	// A graphic control extension gives the frame's delay, then the image descriptor places it.
+	g.w.Write([]byte{0x21, 0xf9, 0x04, 0})
+	g.uint16(gifDelay)
+	g.w.Write([]byte{0, 0, 0x2c})
+	g.uint16(0)
+	g.uint16(0)
+	g.uint16(img.Rect.Dx())
+	g.uint16(img.Rect.Dy())
+	g.w.WriteByte(0)
+
+	// The pixels are LZW compressed with codes starting at 2 bits, the least gif allows,
+	// and written in sub-blocks of up to 255 bytes ended by an empty one.
+	const litWidth = 2
+	g.w.WriteByte(litWidth)
+	blocks := &gifBlocks{w: g.w}
+	lzwWriter := lzw.NewWriter(blocks, lzw.LSB, litWidth)
+	for y := 0; y < img.Rect.Dy(); y++ {
+		if _, err := lzwWriter.Write(img.Pix[y*img.Stride : y*img.Stride+img.Rect.Dx()]); err != nil {
+			return err
+		}
+	}
+	if err := lzwWriter.Close(); err != nil {
+		return err
+	}
+	blocks.flush()
+	return g.w.WriteByte(0)
+}
+
+// close writes the trailer and closes the file.
+func (g *gifWriter) close() error {
+	g.w.WriteByte(0x3b)
+	if err := g.w.Flush(); err != nil {
+		g.file.Close()
+		return err
+	}
+	return g.file.Close()
+}
+
+// gifBlocks splits the data written to it into gif sub-blocks.
+type gifBlocks struct {
+	w   *bufio.Writer
+	buf [255]byte
+	n   int
+}
+
+func (b *gifBlocks) Write(p []byte) (int, error) {
+	for i := range p {
+		b.buf[b.n] = p[i]
+		b.n++
+		if b.n == len(b.buf) {
+			b.flush()
+		}
+	}
+	return len(p), nil
+}
+
+// flush writes the bytes held as a sub-block.
+func (b *gifBlocks) flush() {
+	if b.n > 0 {
+		b.w.WriteByte(byte(b.n))
+		b.w.Write(b.buf[:b.n])
+		b.n = 0
+	}
+}
@@ -1,15 +1,109 @@
 package gol
 
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"time"
+)
+
 // Params provides the details of how to run the Game of Life and which image to load.
+// If Broker holds the address of a broker the turns are computed by its workers instead of locally.
+// With HaloExchange set each worker keeps its strip between turns and swaps edge rows with its neighbours.
+// Input is the path of the PGM image or pattern file to load, images/<h>x<w>.pgm by default,
+// and the size and rule are taken from its header where they are left at zero (see InputParams).
+// Images are saved to OutputDir, out by default, and SnapshotFormat is the pattern format,
+// rle, cells or lif, of the snapshot saved next to each PGM image. It defaults to rle.
+// With PNG set a PNG image is saved there too, drawing each cell as a Scale by Scale square.
+// A checkpoint to resume from is saved there as <h>x<w>.ckpt every CheckpointInterval, if it is
+// positive, and whenever an image is saved with 's'. Naming a checkpoint as the Input resumes the run
+// from it, with its rule and boundary, and the turns carry on from the turn it was saved at up to Turns.
+// TurnsPerSecond caps how fast the turns run, with 0 for no cap, and can be changed with '+' and '-'.
+// The cells flipped in a turn are sent in a CellsFlipped event for each strip of rows a worker computes,
+// or in a RowsFlipped event for each strip if FlipRuns is set. The most recent turns are kept so a paused
+// run can be stepped back through them, from the start of the run if Rewind is set and otherwise from the
+// first time it is paused, sparing a run that is never paused the cost of keeping them.
 type Params struct {
-	Turns       int
-	Threads     int
-	ImageWidth  int
-	ImageHeight int
+	Turns              int
+	Threads            int
+	ImageWidth         int
+	ImageHeight        int
+	Rule               Rule
+	Boundary           Boundary
+	Broker             string
+	HaloExchange       bool
+	Input              string
+	OutputDir          string
+	SnapshotFormat     string
+	PNG                bool
+	Scale              int
+	CheckpointInterval time.Duration
+	TurnsPerSecond     float64
+	FlipRuns           bool
+	Rewind             bool
+}
+
+// inputPath returns p.Input, or the path of the image for the board size if it is empty.
+func (p Params) inputPath() string {
+	if p.Input == "" {
+		return filepath.Join("images", fmt.Sprintf("%vx%v.pgm", p.ImageHeight, p.ImageWidth))
+	}
+	return p.Input
+}
+
+// outputDir returns p.OutputDir, or out if it is empty.
+func (p Params) outputDir() string {
+	if p.OutputDir == "" {
+		return "out"
+	}
+	return p.OutputDir
+}
+
+// checkpointPath returns the path checkpoints of the run are saved to.
+func (p Params) checkpointPath() string {
+	return filepath.Join(p.outputDir(), fmt.Sprintf("%vx%v%v", p.ImageHeight, p.ImageWidth, checkpointExtension))
+}
+
+// scale returns p.Scale, or 1 if it is not positive.
+func (p Params) scale() int {
+	if p.Scale < 1 {
+		return 1
+	}
+	return p.Scale
+}
+
+// snapshotExtension returns the file extension for p.SnapshotFormat.
+func (p Params) snapshotExtension() string {
+	if p.SnapshotFormat == "" {
+		return ".rle"
+	}
+	return "." + p.SnapshotFormat
 }
 
 // Run starts the processing of Game of Life. It should initialise channels and goroutines.
-func Run(p Params, events chan<- Event, keyPresses <-chan rune) {
+// If the run cannot start or fails part way through, Run sends the error in a RunError event,
+// closes events and returns the error, leaving the process running.
+// The keys pressed on keyPresses control the run as KeyCommands describes.
+func Run(p Params, events chan<- Event, keyPresses <-chan rune) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	return RunContext(ctx, p, events, KeyCommands(ctx, keyPresses))
+}
+
+// RunContext is Run controlled by the commands sent on controls rather than by key presses, and
+// stopped as if sent Quit when ctx is cancelled. The board is saved and the events channel closed as
+// usual, and RunContext returns ctx.Err(). Every goroutine the run started has stopped, or is about to,
+// by the time it returns, and so controls may be nil.
+func RunContext(ctx context.Context, p Params, events chan<- Event, controls <-chan Command) error {
+	p, err := InputParams(p)
+	if err == nil {
+		_, err = formatOf(p.snapshotExtension())
+	}
+	if err != nil {
+		events <- RunError{Err: err}
+		close(events)
+		return err
+	}
 
 	//	TODO: Put the missing channels in here.
 
@@ -18,23 +112,38 @@ func Run(p Params, events chan<- Event, keyPresses <-chan rune) {
 	ioFilename := make(chan string)
 	ioOutput := make(chan uint8)
 	ioInput := make(chan uint8)
+	ioCheckpoint := make(chan checkpoint)
+	ioTurn := make(chan int)
+	ioError := make(chan error)
+	ioPattern := make(chan *pattern)
 
 	ioChannels := ioChannels{
-		command:  ioCommand,
-		idle:     ioIdle,
-		filename: ioFilename,
-		output:   ioOutput,
-		input:    ioInput,
+		command:    ioCommand,
+		idle:       ioIdle,
+		filename:   ioFilename,
+		output:     ioOutput,
+		input:      ioInput,
+		checkpoint: ioCheckpoint,
+		turn:       ioTurn,
+		err:        ioError,
+		pattern:    ioPattern,
 	}
 	go startIo(p, ioChannels)
 
 	distributorChannels := distributorChannels{
-		events:     events,
-		ioCommand:  ioCommand,
-		ioIdle:     ioIdle,
-		ioFilename: ioFilename,
-		ioOutput:   ioOutput,
-		ioInput:    ioInput,
-	}
-	distributor(p, distributorChannels, keyPresses)
+		events:       events,
+		ioCommand:    ioCommand,
+		ioIdle:       ioIdle,
+		ioFilename:   ioFilename,
+		ioOutput:     ioOutput,
+		ioInput:      ioInput,
+		ioCheckpoint: ioCheckpoint,
+		ioTurn:       ioTurn,
+		ioError:      ioError,
+		ioPattern:    ioPattern,
+	}
+	if err := distributor(ctx, p, distributorChannels, controls); err != nil {
+		return err
+	}
+	return ctx.Err()
 }
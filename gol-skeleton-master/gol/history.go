@@ -0,0 +1,144 @@
+package gol
+
+import "uk.ac.bris.cs/gameoflife/util"
+
+const (
+	// keyframeInterval is the number of turns between the keyframes of a history.
+	keyframeInterval = 64
+	// historyTurns is the most turns a history keeps.
+	historyTurns = 1024
+	// historyCells is the most flipped cells a history keeps, so a busy board cannot fill the memory.
+	historyCells = 1 << 22
+)
+
+// history keeps the most recent turns of a run so the distributor can step back through them while paused.
+// It is a ring of segments, each holding a keyframe copy of the board followed by the cells that flipped
+// in each turn after it, exactly as they were sent in CellsFlipped events. The oldest segment is dropped
+// as a whole once the history holds more than historyTurns turns or historyCells cells, so the history
+// always starts at a keyframe. A segment is closed early if its next turn would take the history over
+// historyCells, so only a single turn that flips more cells than that by itself is ever kept over it.
+// Dropped segments are kept as spares and their keyframe and buffers reused, so a long run stops
+// allocating once the history is full.
+type history struct {
+	segments []historySegment
+	spares   []historySegment
+	turns    int
+	cells    int
+}
+
+// historySegment is the board after turn start, and the cells that flipped from turn start+i
+// in cells[ends[i-1]:ends[i]].
+type historySegment struct {
+	start    int
+	keyframe *board
+	cells    []util.Cell
+	ends     []int
+}
+
+// turns returns the number of turns the segment holds.
+func (s *historySegment) turns() int {
+	return len(s.ends)
+}
+
+// record adds the turn from world, the board after turn turns, that flipped the cells in flipped.
+// Neither is kept, as the distributor reuses them.
+func (h *history) record(world *board, turn int, flipped []util.Cell) {
+	if len(h.segments) > 0 && h.latest() != turn {
+		// The run jumped, so the turns kept no longer lead up to it.
+		h.clear()
+	}
+	// Starting a new segment lets the oldest be dropped to make room for the turn.
+	last := len(h.segments) - 1
+	if last < 0 || h.segments[last].turns() == keyframeInterval || h.cells+len(flipped) > historyCells {
+		h.segments = append(h.segments, h.newSegment(world, turn))
+		last = len(h.segments) - 1
+	}
+	segment := &h.segments[last]
+	segment.cells = append(segment.cells, flipped...)
+	segment.ends = append(segment.ends, len(segment.cells))
+	h.turns++
+	h.cells += len(flipped)
+
+	for len(h.segments) > 1 && (h.turns > historyTurns || h.cells > historyCells) {
+		h.turns -= h.segments[0].turns()
+		h.cells -= len(h.segments[0].cells)
+		h.spares = append(h.spares, h.segments[0])
+		copy(h.segments, h.segments[1:])
+		h.segments[len(h.segments)-1] = historySegment{}
+		h.segments = h.segments[:len(h.segments)-1]
+	}
+}
+
+// newSegment returns a segment starting from world after turn, reusing a spare one if there is one that fits.
+func (h *history) newSegment(world *board, turn int) historySegment {
+	for len(h.spares) > 0 {
+		spare := h.spares[len(h.spares)-1]
+		h.spares[len(h.spares)-1] = historySegment{}
+		h.spares = h.spares[:len(h.spares)-1]
+		if len(spare.keyframe.words) == len(world.words) && spare.keyframe.width == world.width {
+			copy(spare.keyframe.words, world.words)
+			spare.keyframe.boundary = world.boundary
+			return historySegment{start: turn, keyframe: spare.keyframe, cells: spare.cells[:0], ends: spare.ends[:0]}
+		}
+	}
+	return historySegment{start: turn, keyframe: world.clone(), ends: make([]int, 0, keyframeInterval)}
+}
+
+// clear drops every turn held, for when the board has been changed outside of a turn.
+func (h *history) clear() {
+	h.spares = append(h.spares, h.segments...)
+	h.segments, h.turns, h.cells = h.segments[:0], 0, 0
+}
+
+// oldest returns the earliest turn the history can go back to.
+func (h *history) oldest() int {
+	if len(h.segments) == 0 {
+		return 0
+	}
+	return h.segments[0].start
+}
+
+// latest returns the turn after the last one recorded.
+func (h *history) latest() int {
+	if len(h.segments) == 0 {
+		return 0
+	}
+	last := &h.segments[len(h.segments)-1]
+	return last.start + last.turns()
+}
+
+// holds reports whether the history can rebuild the board after turn.
+func (h *history) holds(turn int) bool {
+	return len(h.segments) > 0 && turn >= h.oldest() && turn <= h.latest()
+}
+
+// segment returns the segment holding turn, which must be from oldest up to latest.
+func (h *history) segment(turn int) *historySegment {
+	for i := len(h.segments) - 1; i > 0; i-- {
+		if h.segments[i].start <= turn {
+			return &h.segments[i]
+		}
+	}
+	return &h.segments[0]
+}
+
+// delta returns the cells that flipped going from turn to turn+1, for a turn from oldest up to latest.
+func (h *history) delta(turn int) []util.Cell {
+	segment := h.segment(turn)
+	i := turn - segment.start
+	if i == 0 {
+		return segment.cells[:segment.ends[0]]
+	}
+	return segment.cells[segment.ends[i-1]:segment.ends[i]]
+}
+
+// boardAt rebuilds the board after turn, from oldest up to latest, from the keyframe before it.
+func (h *history) boardAt(turn int) *board {
+	segment := h.segment(turn)
+	world := segment.keyframe.clone()
+	if turn > segment.start {
+		world.flip(segment.cells[:segment.ends[turn-segment.start-1]])
+	}
+	world.fillGhosts()
+	return world
+}
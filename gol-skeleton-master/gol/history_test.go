@@ -0,0 +1,107 @@
+package gol
+
+import (
+	"bytes"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// TestHistory checks that a history rebuilds the board after every turn it holds,
+// and that it drops its oldest turns a keyframe at a time.
+func TestHistory(t *testing.T) {
+	p := Params{ImageWidth: 70, ImageHeight: 30, Threads: 3}
+	pool := newWorkerPool(p)
+	defer pool.stop()
+	world := newBoard(p.ImageWidth, p.ImageHeight, p.Boundary)
+	world.fromBytes(randomWorld(p.ImageWidth, p.ImageHeight, 3))
+
+	const start, turns = 5, historyTurns + 2*keyframeInterval + 10
+	var h history
+	var boards [][][]byte
+	for turn := start; turn < start+turns; turn++ {
+		boards = append(boards, world.toBytes())
+		next, flipped, _ := pool.step(world)
+		h.record(world, turn, flipped)
+		world = next
+	}
+	boards = append(boards, world.toBytes())
+
+	if h.latest() != start+turns {
+		t.Fatalf("the history ends at turn %v, expected %v", h.latest(), start+turns)
+	}
+	if oldest := h.oldest(); oldest <= start || (oldest-start)%keyframeInterval != 0 || h.latest()-oldest > historyTurns {
+		t.Fatalf("the history starts at turn %v", oldest)
+	}
+	if h.holds(h.oldest()-1) || h.holds(h.latest()+1) || !h.holds(h.latest()) {
+		t.Fatalf("the history holds the wrong turns")
+	}
+	for turn := h.oldest(); turn <= h.latest(); turn++ {
+		got := bytes.Join(h.boardAt(turn).toBytes(), nil)
+		if !bytes.Equal(got, bytes.Join(boards[turn-start], nil)) {
+			t.Fatalf("the board rebuilt for turn %v is wrong", turn)
+		}
+	}
+
+	// Stepping back one turn at a time undoes each turn's flips.
+	back := h.boardAt(h.latest())
+	for turn := h.latest() - 1; turn >= h.oldest(); turn-- {
+		back.flip(h.delta(turn))
+		if !bytes.Equal(bytes.Join(back.toBytes(), nil), bytes.Join(boards[turn-start], nil)) {
+			t.Fatalf("stepping back to turn %v gave the wrong board", turn)
+		}
+	}
+}
+
+// TestHistoryNoAllocs checks that once the history is full a running turn, computed and recorded,
+// allocates nothing, as the segments dropped are reused.
+func TestHistoryNoAllocs(t *testing.T) {
+	p := Params{Threads: 4, ImageWidth: 256, ImageHeight: 256}
+	pool := newWorkerPool(p)
+	defer pool.stop()
+	world := newBoard(p.ImageWidth, p.ImageHeight, p.Boundary)
+	world.fromBytes(randomWorld(p.ImageWidth, p.ImageHeight, 1))
+
+	var h history
+	turn := 0
+	step := func() {
+		next, flipped, _ := pool.step(world)
+		h.record(world, turn, flipped)
+		world = next
+		turn++
+	}
+	for turn < 2*historyTurns+keyframeInterval {
+		step()
+	}
+
+	if allocs := testing.AllocsPerRun(200, step); allocs != 0 {
+		t.Errorf("expected no allocations per turn, got %v", allocs)
+	}
+}
+
+// TestHistoryCellBudget checks that a history keeps to historyCells even when the turns that would
+// take it over all fall within one segment, and still rebuilds the boards it holds.
+func TestHistoryCellBudget(t *testing.T) {
+	world := newBoard(1, 1, Torus)
+	// An odd number of flips of the one cell leaves it flipped, so it is alive after every odd turn.
+	flipped := make([]util.Cell, historyCells/3|1)
+
+	var h history
+	const turns = 10
+	for turn := 0; turn < turns; turn++ {
+		h.record(world, turn, flipped)
+		world.flip(flipped)
+		if h.cells > historyCells {
+			t.Fatalf("the history holds %v cells after turn %v, expected at most %v", h.cells, turn+1, historyCells)
+		}
+	}
+
+	if h.latest() != turns || h.oldest() <= 0 {
+		t.Fatalf("the history holds turns %v to %v", h.oldest(), h.latest())
+	}
+	for turn := h.oldest(); turn <= h.latest(); turn++ {
+		if got := h.boardAt(turn).get(0, 0); got != (turn%2 == 1) {
+			t.Fatalf("the board rebuilt for turn %v is wrong", turn)
+		}
+	}
+}
@@ -3,18 +3,20 @@ package gol
 import (
 	"fmt"
 	"os"
-	"strconv"
-	"strings"
-	"uk.ac.bris.cs/gameoflife/util"
+	"path/filepath"
 )
 
 type ioChannels struct {
 	command <-chan ioCommand
 	idle    chan<- bool
 
-	filename <-chan string
-	output   <-chan uint8
-	input    chan<- uint8
+	filename   <-chan string
+	output     <-chan uint8
+	input      chan<- uint8
+	checkpoint <-chan checkpoint
+	turn       chan<- int
+	err        chan<- error
+	pattern    chan<- *pattern
 }
 
 // ioState is the internal ioState of the io goroutine.
@@ -32,31 +34,38 @@ type ioCommand uint8
 //	ioOutput 	= 0
 //	ioInput 	= 1
 //	ioCheckIdle = 2
+//	ioCheckpoint = 3
+//	ioExport 	= 4
+//	ioPattern 	= 5
 const (
 	ioOutput ioCommand = iota
 	ioInput
 	ioCheckIdle
+	ioCheckpoint
+	ioExport
+	ioPattern
 )
 
-// writePgmImage receives an array of bytes and writes it to a pgm file.
-func (io *ioState) writePgmImage() {
-	_ = os.Mkdir("out", os.ModePerm)
+// OutputError is returned when an image, snapshot or checkpoint cannot be written to Path.
+type OutputError struct {
+	Path string
+	Err  error
+}
 
-	// Request a filename from the distributor.
-	filename := <-io.channels.filename
+func (e *OutputError) Error() string {
+	return fmt.Sprintf("writing %v: %v", e.Path, e.Err)
+}
 
-	file, ioError := os.Create("out/" + filename + ".pgm")
-	util.Check(ioError)
-	defer file.Close()
+func (e *OutputError) Unwrap() error {
+	return e.Err
+}
 
-	_, _ = file.WriteString("P5\n")
-	//_, _ = file.WriteString("# PGM file writer by pnmmodules (https://github.com/owainkenwayucl/pnmmodules).\n")
-	_, _ = file.WriteString(strconv.Itoa(io.params.ImageWidth))
-	_, _ = file.WriteString(" ")
-	_, _ = file.WriteString(strconv.Itoa(io.params.ImageHeight))
-	_, _ = file.WriteString("\n")
-	_, _ = file.WriteString(strconv.Itoa(255))
-	_, _ = file.WriteString("\n")
+// writePgmImage receives an array of bytes and writes it to a pgm file,
+// with a snapshot of the same board next to it in the pattern format chosen by the params
+// and a png image if they ask for one. It replies with any error once the files are written.
+func (io *ioState) writePgmImage() {
+	// Request a filename from the distributor.
+	filename := <-io.channels.filename
 
 	world := make([][]byte, io.params.ImageHeight)
 	for i := range world {
@@ -65,64 +74,220 @@ func (io *ioState) writePgmImage() {
 
 	for y := 0; y < io.params.ImageHeight; y++ {
 		for x := 0; x < io.params.ImageWidth; x++ {
-			val := <-io.channels.output
-			//if val != 0 {
-			//	fmt.Println(x, y)
-			//}
-			world[y][x] = val
+			world[y][x] = <-io.channels.output
 		}
 	}
 
-	for y := 0; y < io.params.ImageHeight; y++ {
-		for x := 0; x < io.params.ImageWidth; x++ {
-			_, ioError = file.Write([]byte{world[y][x]})
-			util.Check(ioError)
-		}
+	io.channels.err <- io.writeImages(filename, world)
+}
+
+// writeImages writes world to the output directory as filename.pgm, with its snapshot and png image.
+func (io *ioState) writeImages(filename string, world [][]byte) error {
+	dir := io.params.outputDir()
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return &OutputError{dir, err}
 	}
 
-	ioError = file.Sync()
-	util.Check(ioError)
+	path := filepath.Join(dir, filename+".pgm")
+	if err := writePgmFile(path, world); err != nil {
+		return &OutputError{path, err}
+	}
+	path = filepath.Join(dir, filename+io.params.snapshotExtension())
+	if err := writePatternFile(path, world, io.params.Rule); err != nil {
+		return &OutputError{path, err}
+	}
+	if io.params.PNG {
+		path = filepath.Join(dir, filename+".png")
+		if err := writePngFile(path, world, io.params.scale()); err != nil {
+			return &OutputError{path, err}
+		}
+	}
 
 	fmt.Println("File", filename, "output done!")
+	return nil
 }
 
-// readPgmImage opens a pgm file and sends its data as an array of bytes.
-func (io *ioState) readPgmImage() {
+// writeCheckpoint receives a checkpoint from the distributor and saves it over the last one,
+// replying with any error.
+func (io *ioState) writeCheckpoint() {
+	c := <-io.channels.checkpoint
+	dir := io.params.outputDir()
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		io.channels.err <- &OutputError{dir, err}
+		return
+	}
+	path := io.params.checkpointPath()
+	if err := writeCheckpointFile(path, c); err != nil {
+		io.channels.err <- &OutputError{path, err}
+		return
+	}
 
-	// Request a filename from the distributor.
+	fmt.Println("Checkpoint at turn", c.turn, "output done!")
+	io.channels.err <- nil
+}
+
+// exportBoard receives a path and a checkpoint from the distributor and saves the board of the
+// checkpoint to the path alone, replying with any error.
+func (io *ioState) exportBoard() {
+	path := <-io.channels.filename
+	c := <-io.channels.checkpoint
+	io.channels.err <- writeBoardFile(path, c, io.params.scale())
+}
+
+// writeBoardFile writes the board of c to path as a checkpoint, a pgm or png image or a pattern,
+// as the extension of path names.
+func writeBoardFile(path string, c checkpoint, scale int) error {
+	var err error
+	switch ext := filepath.Ext(path); {
+	case isCheckpointFile(path):
+		err = writeCheckpointFile(path, c)
+	case isPatternFile(path):
+		err = writePatternFile(path, c.world, c.rule)
+	case ext == ".pgm":
+		err = writePgmFile(path, c.world)
+	case ext == ".png":
+		err = writePngFile(path, c.world, scale)
+	default:
+		err = fmt.Errorf("%q is not the extension of an image, checkpoint or pattern file", ext)
+	}
+	if err != nil {
+		return &OutputError{path, err}
+	}
+	return nil
+}
+
+// loadPattern reads the pattern file named by the distributor, replying with any error
+// and then sending the pattern if there is none.
+func (io *ioState) loadPattern() {
 	filename := <-io.channels.filename
+	pattern, err := readPatternFile(filename)
+	io.channels.err <- err
+	if err == nil {
+		io.channels.pattern <- pattern
+	}
+}
 
-	data, ioError := os.ReadFile("images/" + filename + ".pgm")
-	util.Check(ioError)
+// readImage reads the file named by the distributor and replies with any error. If there is none
+// it sends the board as an array of bytes, followed by the number of turns the board has been through.
+// A name ending in .rle, .cells, .lif or .life is read as a pattern in that format,
+// one ending in .ckpt as a checkpoint, and any other name as a pgm or pbm image.
+func (io *ioState) readImage() {
+	// Request a filename from the distributor.
+	filename := <-io.channels.filename
 
-	fields := strings.Fields(string(data))
+	var world [][]byte
+	turn := 0
+	var err error
+	switch {
+	case isPatternFile(filename):
+		world, err = io.readPattern(filename)
+	case isCheckpointFile(filename):
+		world, turn, err = io.readCheckpoint(filename)
+	default:
+		world, err = io.readPgmImage(filename)
+	}
+	io.channels.err <- err
+	if err != nil {
+		return
+	}
 
-	if fields[0] != "P5" {
-		panic("Not a pgm file")
+	for _, row := range world {
+		for _, b := range row {
+			io.channels.input <- b
+		}
 	}
+	io.channels.turn <- turn
+
+	fmt.Println("File", filename, "input done!")
+}
 
-	width, _ := strconv.Atoi(fields[1])
-	if width != io.params.ImageWidth {
-		panic("Incorrect width")
+// readCheckpoint reads the board of a checkpoint and the number of turns it has been through.
+func (io *ioState) readCheckpoint(filename string) ([][]byte, int, error) {
+	c, err := readCheckpointFile(filename)
+	if err != nil {
+		return nil, 0, err
 	}
+	if len(c.world) != io.params.ImageHeight || len(c.world[0]) != io.params.ImageWidth {
+		return nil, 0, &SizeError{filename, len(c.world[0]), len(c.world), io.params.ImageWidth, io.params.ImageHeight}
+	}
+	return c.world, c.turn, nil
+}
 
-	height, _ := strconv.Atoi(fields[2])
-	if height != io.params.ImageHeight {
-		panic("Incorrect height")
+// readPattern reads a pattern file centred on the board.
+func (io *ioState) readPattern(filename string) ([][]byte, error) {
+	pattern, err := readPatternFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	world, err := pattern.centre(io.params.ImageWidth, io.params.ImageHeight)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", filename, err)
 	}
+	return world, nil
+}
 
-	maxval, _ := strconv.Atoi(fields[3])
-	if maxval != 255 {
-		panic("Incorrect maxval/bit depth")
+// readPgmImage reads the cells of a pgm or pbm image.
+func (io *ioState) readPgmImage(filename string) ([][]byte, error) {
+	width, height, image, err := readPgmFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	if width != io.params.ImageWidth || height != io.params.ImageHeight {
+		return nil, &SizeError{filename, width, height, io.params.ImageWidth, io.params.ImageHeight}
 	}
 
-	image := []byte(fields[4])
+	world := make([][]byte, height)
+	for y := range world {
+		world[y] = image[y*width : (y+1)*width]
+	}
+	return world, nil
+}
 
-	for _, b := range image {
-		io.channels.input <- b
+// InputParams fills in the details p leaves open from the header of its input file (see Params.Input).
+// A zero ImageWidth and ImageHeight become the size of the image, pattern or checkpoint, a zero Rule
+// becomes the rule of a pattern, and a checkpoint sets the Rule and Boundary to those it was saved with.
+// It returns an error if the input cannot be read or does not match the size p gives: an image must be
+// exactly that size, and a pattern must fit on the board.
+func InputParams(p Params) (Params, error) {
+	filename := p.inputPath()
+	var width, height int
+	switch {
+	case isPatternFile(filename):
+		pattern, err := readPatternFile(filename)
+		if err != nil {
+			return p, err
+		}
+		if p.ImageWidth == 0 && p.ImageHeight == 0 {
+			p.ImageWidth, p.ImageHeight = pattern.width, pattern.height
+		}
+		if p.Rule == (Rule{}) {
+			p.Rule = pattern.rule
+		}
+		if _, err := pattern.centre(p.ImageWidth, p.ImageHeight); err != nil {
+			return p, fmt.Errorf("%v: %w", filename, err)
+		}
+		return p, nil
+	case isCheckpointFile(filename):
+		c, err := readCheckpointFile(filename)
+		if err != nil {
+			return p, err
+		}
+		width, height = len(c.world[0]), len(c.world)
+		p.Rule, p.Boundary = c.rule, c.boundary
+	default:
+		var err error
+		if width, height, err = readPgmSize(filename); err != nil {
+			return p, err
+		}
 	}
 
-	fmt.Println("File", filename, "input done!")
+	if p.ImageWidth == 0 && p.ImageHeight == 0 {
+		p.ImageWidth, p.ImageHeight = width, height
+	}
+	if width != p.ImageWidth || height != p.ImageHeight {
+		return p, &SizeError{filename, width, height, p.ImageWidth, p.ImageHeight}
+	}
+	return p, nil
 }
 
 // startIo should be the entrypoint of the io goroutine.
@@ -136,11 +301,17 @@ func startIo(p Params, c ioChannels) {
 		// Block and wait for requests from the distributor
 		switch command {
 		case ioInput:
-			io.readPgmImage()
+			io.readImage()
 		case ioOutput:
 			io.writePgmImage()
 		case ioCheckIdle:
 			io.channels.idle <- true
+		case ioCheckpoint:
+			io.writeCheckpoint()
+		case ioExport:
+			io.exportBoard()
+		case ioPattern:
+			io.loadPattern()
 		}
 	}
 }
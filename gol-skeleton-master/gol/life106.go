@@ -0,0 +1,111 @@
+package gol
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+const life106Header = "#Life 1.06"
+
+// readLife106 reads a pattern in the Life 1.06 format: a "#Life 1.06" line followed by
+// one "x y" line for every live cell. The coordinates are relative to an origin, which
+// is placed at the centre of the board, so the pattern is as big as it needs to be for
+// the origin to sit in the middle of it.
+func readLife106(r io.Reader) (*pattern, error) {
+	scanner := bufio.NewScanner(r)
+	if !scanner.Scan() || strings.TrimSpace(scanner.Text()) != life106Header {
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("life 1.06: the first line must be %q", life106Header)
+	}
+
+	var cells []util.Cell
+	minX, minY, maxX, maxY := 0, 0, 0, 0
+	for line := 2; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Fields(text)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("life 1.06: line %v: expected two coordinates", line)
+		}
+		x, err := strconv.Atoi(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("life 1.06: line %v: %w", line, err)
+		}
+		y, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("life 1.06: line %v: %w", line, err)
+		}
+		// The coordinates are kept to those of the largest board with the origin in the middle,
+		// which also keeps the size of the pattern from overflowing.
+		if x < -maxPgmSize/2 || x >= maxPgmSize/2 || y < -maxPgmSize/2 || y >= maxPgmSize/2 {
+			return nil, fmt.Errorf("life 1.06: line %v: the cell (%v, %v) is more than %v cells from the origin",
+				line, x, y, maxPgmSize/2)
+		}
+		if len(cells) == 0 || x < minX {
+			minX = x
+		}
+		if len(cells) == 0 || x > maxX {
+			maxX = x
+		}
+		if len(cells) == 0 || y < minY {
+			minY = y
+		}
+		if len(cells) == 0 || y > maxY {
+			maxY = y
+		}
+		cells = append(cells, util.Cell{X: x, Y: y})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	p := &pattern{anchored: true}
+	p.width, p.originX = aroundOrigin(minX, maxX)
+	p.height, p.originY = aroundOrigin(minY, maxY)
+	for _, cell := range cells {
+		p.alive = append(p.alive, util.Cell{X: cell.X + p.originX, Y: cell.Y + p.originY})
+	}
+	return p, nil
+}
+
+// aroundOrigin returns the smallest size whose centre, size/2, is far enough from both ends
+// to hold the coordinates low to high, along with that centre.
+func aroundOrigin(low, high int) (size, centre int) {
+	if low > 0 {
+		low = 0
+	}
+	if high < 0 {
+		high = 0
+	}
+	size = 2*high + 1
+	if 2*-low > size {
+		size = 2 * -low
+	}
+	return size, size / 2
+}
+
+// writeLife106 writes world, a grid of 255/0 bytes, in the Life 1.06 format with the origin
+// at the centre of the board, so that it reads back onto a board of the same size unchanged.
+// The format has no place for the rule.
+func writeLife106(w io.Writer, world [][]byte, rule Rule) error {
+	out := bufio.NewWriter(w)
+	fmt.Fprintln(out, life106Header)
+	centreX, centreY := rowLength(world)/2, len(world)/2
+	for y, row := range world {
+		for x, cell := range row {
+			if cell == 255 {
+				fmt.Fprintf(out, "%v %v\n", x-centreX, y-centreY)
+			}
+		}
+	}
+	return out.Flush()
+}
@@ -0,0 +1,60 @@
+package gol
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestReadLife106(t *testing.T) {
+	glider := "#Life 1.06\n0 -1\n1 0\n-1 1\n0 1\n1 1\n"
+	p, err := readLife106(strings.NewReader(glider))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.width != 3 || p.height != 3 || p.originX != 1 || p.originY != 1 {
+		t.Fatalf("got a %vx%v pattern with its origin at (%v, %v)", p.width, p.height, p.originX, p.originY)
+	}
+	world, err := p.centre(5, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(world[1:4]) != "[[0 0 255 0 0] [0 0 0 255 0] [0 255 255 255 0]]" {
+		t.Fatalf("got the board %v", world)
+	}
+
+	for _, bad := range []string{
+		"", "0 0\n", "#Life 1.06\n0\n", "#Life 1.06\n0 x\n",
+		// Cells too far out for any board, or whose coordinates overflow, are refused.
+		"#Life 1.06\n32768 0\n", "#Life 1.06\n0 -32769\n",
+		"#Life 1.06\n-9223372036854775808 9223372036854775807\n", "#Life 1.06\n99999999999999999999 0\n",
+	} {
+		if _, err := readLife106(strings.NewReader(bad)); err == nil {
+			t.Errorf("expected an error reading %q", bad)
+		}
+	}
+}
+
+func TestLife106RoundTrip(t *testing.T) {
+	for _, size := range [][2]int{{16, 16}, {1, 1}, {31, 8}} {
+		width, height := size[0], size[1]
+		world := randomWorld(width, height, int64(width*height))
+
+		var b bytes.Buffer
+		if err := writeLife106(&b, world, Rule{}); err != nil {
+			t.Fatal(err)
+		}
+		p, err := readLife106(&b)
+		if err != nil {
+			t.Fatalf("%dx%d: %v", width, height, err)
+		}
+		got, err := p.centre(width, height)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(bytes.Join(got, nil), bytes.Join(world, nil)) {
+			t.Fatalf("%dx%d: the board read back differs from the one written", width, height)
+		}
+	}
+}
@@ -0,0 +1,260 @@
+package gol
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/rpc"
+	"sync"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// workerNode is the RPC service run by a distributed worker.
+// In a broker-centric run every Step call carries the rows it needs and the node keeps no state.
+// In a halo-exchange run the node owns a strip loaded once with Load and moves it on with Advance.
+type workerNode struct {
+	mu       sync.Mutex
+	listener net.Listener
+	strip    *haloStrip
+	clients  map[string]*rpc.Client
+}
+
+// haloStrip is the strip of the board owned by a worker node in a halo-exchange run.
+type haloStrip struct {
+	args    LoadArgs
+	rule    *ruleTable
+	turn    int
+	current *board
+	next    *board
+	flipped []util.Cell
+	// above and below fetch the neighbouring strips' edge rows.
+	above, below *rpc.Client
+	// edges[turn%2] holds copies of the strip's top and bottom rows after that turn, so a neighbour
+	// that is one turn behind can still fetch them after this strip has moved on.
+	edges [2][2][]uint64
+}
+
+// Step computes one turn of the strip in args using the same kernel as the local workers.
+func (w *workerNode) Step(args StepArgs, reply *StepReply) error {
+	start := time.Now()
+	rows := args.EndY - args.StartY
+	current := boardFromWords(args.Width, rows, Torus, args.Rows)
+	next := newBoard(args.Width, rows, Torus)
+
+	reply.Flipped = current.step(next, newRuleTable(args.Rule), 0, rows, nil)
+	reply.Elapsed = time.Since(start)
+	for i := range reply.Flipped {
+		reply.Flipped[i].Y += args.StartY
+	}
+	reply.Rows = next.words[next.stride : (rows+1)*next.stride]
+	return nil
+}
+
+// Load takes ownership of the strip in args, replacing any strip loaded before.
+func (w *workerNode) Load(args LoadArgs, reply *LoadReply) error {
+	above, err := w.client(args.Above)
+	if err != nil {
+		return err
+	}
+	below, err := w.client(args.Below)
+	if err != nil {
+		return err
+	}
+
+	rows := args.EndY - args.StartY
+	current := newBoard(args.Width, rows, args.Boundary)
+	copy(current.words[current.stride:], args.Rows)
+	strip := &haloStrip{
+		args:    args,
+		rule:    newRuleTable(args.Rule),
+		turn:    args.CompletedTurns,
+		current: current,
+		next:    newBoard(args.Width, rows, args.Boundary),
+		above:   above,
+		below:   below,
+	}
+	strip.saveEdges()
+
+	w.mu.Lock()
+	w.strip = strip
+	w.mu.Unlock()
+	return nil
+}
+
+// Advance fetches the rows either side of the strip from the neighbouring workers
+// and moves the strip on by one turn.
+func (w *workerNode) Advance(args AdvanceArgs, reply *AdvanceReply) error {
+	w.mu.Lock()
+	strip := w.strip
+	w.mu.Unlock()
+	if strip == nil {
+		return errors.New("worker: no strip loaded")
+	}
+	if strip.turn != args.CompletedTurns {
+		return fmt.Errorf("worker: strip is at turn %v, not %v", strip.turn, args.CompletedTurns)
+	}
+
+	rows := strip.current.height
+	if err := strip.halo(strip.current.row(-1), false); err != nil {
+		return err
+	}
+	if err := strip.halo(strip.current.row(rows), true); err != nil {
+		return err
+	}
+
+	start := time.Now()
+	strip.flipped = strip.current.step(strip.next, strip.rule, 0, rows, strip.flipped[:0])
+	strip.next.fillSideGhosts()
+	reply.Elapsed = time.Since(start)
+	reply.Flipped = make([]util.Cell, len(strip.flipped))
+	for i, cell := range strip.flipped {
+		reply.Flipped[i] = util.Cell{X: cell.X, Y: cell.Y + strip.args.StartY}
+	}
+
+	w.mu.Lock()
+	strip.current, strip.next = strip.next, strip.current
+	strip.turn++
+	strip.saveEdges()
+	w.mu.Unlock()
+	return nil
+}
+
+// Edge returns the top or bottom row of the strip after args.CompletedTurns turns.
+func (w *workerNode) Edge(args EdgeArgs, reply *EdgeReply) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	strip := w.strip
+	if strip == nil {
+		return errors.New("worker: no strip loaded")
+	}
+	if args.CompletedTurns != strip.turn && args.CompletedTurns != strip.turn-1 {
+		return fmt.Errorf("worker: edge for turn %v requested at turn %v", args.CompletedTurns, strip.turn)
+	}
+	side := 0
+	if args.Bottom {
+		side = 1
+	}
+	reply.Row = strip.edges[args.CompletedTurns%2][side]
+	return nil
+}
+
+// Ping lets the broker check that the node is still there.
+func (w *workerNode) Ping(args PingArgs, reply *PingReply) error {
+	return nil
+}
+
+// Shutdown drops the node's strip, hangs up on the other worker nodes and stops the node serving.
+func (w *workerNode) Shutdown(args ShutdownArgs, reply *ShutdownReply) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.strip = nil
+	for address, client := range w.clients {
+		client.Close()
+		delete(w.clients, address)
+	}
+	fmt.Println("Worker shutting down")
+	return w.listener.Close()
+}
+
+// client returns a connection to the worker node at address, dialling it the first time.
+func (w *workerNode) client(address string) (*rpc.Client, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if client, ok := w.clients[address]; ok {
+		return client, nil
+	}
+	client, err := rpc.Dial("tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	if w.clients == nil {
+		w.clients = make(map[string]*rpc.Client)
+	}
+	w.clients[address] = client
+	return client, nil
+}
+
+func (strip *haloStrip) saveEdges() {
+	rows := strip.current.height
+	strip.edges[strip.turn%2] = [2][]uint64{
+		append([]uint64(nil), strip.current.row(0)...),
+		append([]uint64(nil), strip.current.row(rows-1)...),
+	}
+}
+
+// halo fills the ghost row above the strip, or below it, for the current turn.
+// Inside the board it is the neighbouring strip's edge row. At the top and bottom of the board
+// it depends on the boundary, and only the torus and Klein bottle need the strip on the other side.
+func (strip *haloStrip) halo(dst []uint64, below bool) error {
+	atEdge := strip.args.StartY == 0
+	neighbour := strip.above
+	if below {
+		atEdge = strip.args.EndY == strip.args.Height
+		neighbour = strip.below
+	}
+
+	if atEdge {
+		switch strip.args.Boundary {
+		case DeadBorder:
+			for i := range dst {
+				dst[i] = 0
+			}
+			return nil
+		case Reflect:
+			own := strip.current.row(0)
+			if below {
+				own = strip.current.row(strip.current.height - 1)
+			}
+			copy(dst, own)
+			return nil
+		}
+	}
+
+	// The strip above hands over its bottom row and the strip below its top row.
+	var reply EdgeReply
+	err := wait(neighbour.Go(WorkerEdge, EdgeArgs{CompletedTurns: strip.turn, Bottom: !below}, &reply, nil))
+	if err != nil {
+		return err
+	}
+	if atEdge && strip.args.Boundary == KleinBottle {
+		reverseRow(dst, reply.Row, strip.args.Width)
+	} else {
+		copy(dst, reply.Row)
+	}
+	return nil
+}
+
+// ServeWorker runs a worker node on listener until the listener is closed, either directly or by a
+// Shutdown call, and every client has hung up.
+func ServeWorker(listener net.Listener) error {
+	server := rpc.NewServer()
+	if err := server.RegisterName("Worker", &workerNode{listener: listener}); err != nil {
+		return err
+	}
+	serve(server, listener)
+	return nil
+}
+
+// RegisterWorker tells the broker at brokerAddress to use the worker node listening on address.
+// The worker's listener must already be open, but it does not have to be served yet.
+func RegisterWorker(brokerAddress, address string) error {
+	broker, err := rpc.Dial("tcp", brokerAddress)
+	if err != nil {
+		return err
+	}
+	defer broker.Close()
+	return broker.Call(BrokerRegister, RegisterArgs{Address: address}, &RegisterReply{})
+}
+
+// DeregisterWorker tells the broker at brokerAddress to stop using the worker node listening on address.
+// It returns once the broker has shared the node's rows out to other workers, so the node can exit.
+func DeregisterWorker(brokerAddress, address string) error {
+	broker, err := rpc.Dial("tcp", brokerAddress)
+	if err != nil {
+		return err
+	}
+	defer broker.Close()
+	return broker.Call(BrokerDeregister, DeregisterArgs{Address: address}, &DeregisterReply{})
+}
@@ -0,0 +1,127 @@
+package gol
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// pattern is a Life pattern read from a file: the size of its bounding box, the cells alive in it
+// and the rule it was made for. rule is the zero Rule if the file does not say.
+//
+// A pattern is normally centred on the board. If anchored is set, the cell (originX, originY)
+// of the pattern goes at the centre of the board instead, for formats whose coordinates are
+// relative to an origin rather than to a bounding box.
+type pattern struct {
+	width, height    int
+	rule             Rule
+	alive            []util.Cell
+	anchored         bool
+	originX, originY int
+}
+
+// patternFormat reads and writes one pattern file format.
+type patternFormat struct {
+	read  func(r io.Reader) (*pattern, error)
+	write func(w io.Writer, world [][]byte, rule Rule) error
+}
+
+// patternFormats maps each file extension to its format.
+var patternFormats = map[string]patternFormat{
+	".rle":   {readRLE, writeRLE},
+	".cells": {readCells, writeCells},
+	".lif":   {readLife106, writeLife106},
+	".life":  {readLife106, writeLife106},
+}
+
+func formatOf(path string) (patternFormat, error) {
+	format, ok := patternFormats[filepath.Ext(path)]
+	if !ok {
+		return patternFormat{}, fmt.Errorf("%v: not an .rle, .cells, .lif or .life pattern file", path)
+	}
+	return format, nil
+}
+
+// isPatternFile reports whether path names a file in one of the pattern formats.
+func isPatternFile(path string) bool {
+	_, ok := patternFormats[filepath.Ext(path)]
+	return ok
+}
+
+// readPatternFile reads the pattern file at path, in the format given by its extension.
+func readPatternFile(path string) (*pattern, error) {
+	format, err := formatOf(path)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return format.read(file)
+}
+
+// writePatternFile writes world, a grid of 255/0 bytes, to path in the format given by its extension.
+func writePatternFile(path string, world [][]byte, rule Rule) error {
+	format, err := formatOf(path)
+	if err != nil {
+		return err
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := format.write(file, world, rule); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// centre places the pattern in the middle of a width by height grid of 255/0 bytes.
+func (p *pattern) centre(width, height int) ([][]byte, error) {
+	offsetX, offsetY := (width-p.width)/2, (height-p.height)/2
+	if p.anchored {
+		offsetX, offsetY = width/2-p.originX, height/2-p.originY
+	}
+	if offsetX < 0 || offsetY < 0 || offsetX+p.width > width || offsetY+p.height > height {
+		return nil, fmt.Errorf("the %vx%v pattern does not fit on a %vx%v board", p.width, p.height, width, height)
+	}
+	world := make([][]byte, height)
+	for y := range world {
+		world[y] = make([]byte, width)
+	}
+	for _, cell := range p.alive {
+		world[cell.Y+offsetY][cell.X+offsetX] = 255
+	}
+	return world, nil
+}
+
+// paste copies the pattern onto b with the top left corner of its bounding box at (x, y),
+// setting every cell under the box to the pattern's. It returns the cells that flipped.
+// The ghost cells are left for fillGhosts.
+func (p *pattern) paste(b *board, x, y int) ([]util.Cell, error) {
+	if x < 0 || y < 0 || x+p.width > b.width || y+p.height > b.height {
+		return nil, fmt.Errorf("the %vx%v pattern does not fit on a %vx%v board at (%v, %v)",
+			p.width, p.height, b.width, b.height, x, y)
+	}
+	alive := make(map[util.Cell]bool, len(p.alive))
+	for _, cell := range p.alive {
+		alive[cell] = true
+	}
+	var flipped []util.Cell
+	for dy := 0; dy < p.height; dy++ {
+		for dx := 0; dx < p.width; dx++ {
+			cell := util.Cell{X: x + dx, Y: y + dy}
+			if b.get(cell.X, cell.Y) != alive[util.Cell{X: dx, Y: dy}] {
+				flipped = append(flipped, cell)
+			}
+		}
+	}
+	b.flip(flipped)
+	return flipped, nil
+}
@@ -0,0 +1,34 @@
+package gol
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// TestPaste checks that pasting a pattern sets every cell under its bounding box and flips only those that change.
+func TestPaste(t *testing.T) {
+	glider, err := readRLE(strings.NewReader("x = 3, y = 3\nbob$2bo$3o!\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := newBoard(8, 8, Torus)
+	b.set(1, 1, true) // under the box and dead in the glider, so it dies
+	b.set(2, 1, true) // under the box and alive in the glider, so it stays
+	b.set(7, 7, true) // outside the box, so it stays
+
+	flipped, err := glider.paste(b, 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(flipped) != "[{1 1} {3 2} {1 3} {2 3} {3 3}]" {
+		t.Errorf("got the flipped cells %v", flipped)
+	}
+	if alive := fmt.Sprint(b.aliveCells()); alive != "[{2 1} {3 2} {1 3} {2 3} {3 3} {7 7}]" {
+		t.Errorf("got the board %v after pasting", alive)
+	}
+
+	if _, err := glider.paste(b, 6, 0); err == nil {
+		t.Errorf("pasted a 3x3 pattern over the edge of an 8x8 board")
+	}
+}
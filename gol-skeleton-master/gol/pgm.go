@@ -0,0 +1,293 @@
+package gol
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+)
+
+// maxPgmSize is the largest width or height of an image that will be read.
+const maxPgmSize = 1 << 16
+
+// The errors a PgmError wraps, telling which part of an image could not be read.
+var (
+	ErrPgmFormat = errors.New("not a P1, P2, P4 or P5 image")
+	ErrPgmHeader = errors.New("malformed header")
+	ErrPgmPixels = errors.New("malformed pixels")
+)
+
+// PgmError is returned for an image that cannot be read. Err is ErrPgmFormat, ErrPgmHeader or
+// ErrPgmPixels wrapped with the details, or the error from opening or reading the file,
+// so errors.Is(err, fs.ErrNotExist) reports a missing image.
+type PgmError struct {
+	Filename string
+	Err      error
+}
+
+func (e *PgmError) Error() string {
+	return fmt.Sprintf("%v: %v", e.Filename, e.Err)
+}
+
+func (e *PgmError) Unwrap() error {
+	return e.Err
+}
+
+// SizeError is returned for an input that does not match the size of the board asked for.
+type SizeError struct {
+	Filename                string
+	Width, Height           int
+	BoardWidth, BoardHeight int
+}
+
+func (e *SizeError) Error() string {
+	return fmt.Sprintf("%v: the image is %vx%v but the board is %vx%v",
+		e.Filename, e.Width, e.Height, e.BoardWidth, e.BoardHeight)
+}
+
+// pgmHeader is the header of a netpbm image. maxval is 1 for the P1 and P4 bitmaps.
+type pgmHeader struct {
+	magic         string
+	width, height int
+	maxval        int
+}
+
+// readPgmFile reads the image at path, returning its size and its cells as 255/0 bytes.
+func readPgmFile(path string) (width, height int, image []byte, err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, 0, nil, &PgmError{Filename: path, Err: err}
+	}
+	defer file.Close()
+
+	r := bufio.NewReader(file)
+	header, err := readPgmHeader(r)
+	if err == nil {
+		image, err = readPgmPixels(r, header)
+	}
+	if err != nil {
+		return 0, 0, nil, &PgmError{Filename: path, Err: err}
+	}
+	return header.width, header.height, image, nil
+}
+
+// writePgmFile writes world, a grid of 255/0 bytes, to path as a P5 image.
+func writePgmFile(path string, world [][]byte) error {
+	width := 0
+	if len(world) > 0 {
+		width = len(world[0])
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	out := bufio.NewWriter(file)
+	fmt.Fprintf(out, "P5\n%v %v\n255\n", width, len(world))
+	for _, row := range world {
+		out.Write(row)
+	}
+	if err := out.Flush(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// readPgmSize reads the header of the image at path and returns its size.
+func readPgmSize(path string) (width, height int, err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, 0, &PgmError{Filename: path, Err: err}
+	}
+	defer file.Close()
+
+	header, err := readPgmHeader(bufio.NewReader(file))
+	if err != nil {
+		return 0, 0, &PgmError{Filename: path, Err: err}
+	}
+	return header.width, header.height, nil
+}
+
+// readPgmHeader reads the header of a P1, P2, P4 or P5 image up to the single whitespace
+// character before its pixels. # starts a comment that runs to the end of the line.
+func readPgmHeader(r *bufio.Reader) (pgmHeader, error) {
+	var header pgmHeader
+	magic := make([]byte, 2)
+	if _, err := io.ReadFull(r, magic); err != nil {
+		return header, fmt.Errorf("%w: %v", ErrPgmFormat, err)
+	}
+	header.magic = string(magic)
+	switch header.magic {
+	case "P1", "P4":
+		header.maxval = 1
+	case "P2", "P5":
+	default:
+		return header, fmt.Errorf("%w: magic number %q", ErrPgmFormat, header.magic)
+	}
+
+	fields := []struct {
+		name  string
+		value *int
+		max   int
+	}{
+		{"width", &header.width, maxPgmSize},
+		{"height", &header.height, maxPgmSize},
+		{"maxval", &header.maxval, 65535},
+	}
+	if header.maxval == 1 {
+		fields = fields[:2]
+	}
+	for _, field := range fields {
+		token, err := readPgmToken(r)
+		if err != nil {
+			return header, fmt.Errorf("%w: reading the %v: %v", ErrPgmHeader, field.name, err)
+		}
+		n, err := strconv.Atoi(token)
+		if err != nil || n <= 0 || n > field.max {
+			return header, fmt.Errorf("%w: %v %q is not between 1 and %v", ErrPgmHeader, field.name, token, field.max)
+		}
+		*field.value = n
+	}
+	return header, nil
+}
+
+// readPgmToken skips whitespace and comments and reads the next token,
+// along with the whitespace character or comment that ends it.
+func readPgmToken(r *bufio.Reader) (string, error) {
+	var token []byte
+	for {
+		b, err := r.ReadByte()
+		if err == io.EOF && len(token) > 0 {
+			return string(token), nil
+		}
+		if err != nil {
+			return "", err
+		}
+		switch {
+		case b == '#':
+			if _, err := r.ReadBytes('\n'); err != nil && err != io.EOF {
+				return "", err
+			}
+			if len(token) > 0 {
+				return string(token), nil
+			}
+		case isPgmSpace(b):
+			if len(token) > 0 {
+				return string(token), nil
+			}
+		default:
+			token = append(token, b)
+		}
+	}
+}
+
+func isPgmSpace(b byte) bool {
+	switch b {
+	case ' ', '\t', '\n', '\v', '\f', '\r':
+		return true
+	}
+	return false
+}
+
+// readPgmPixels reads the pixels after the header and thresholds them into 255/0 bytes:
+// a grey level above half of maxval is alive. Bitmaps draw alive cells white like the
+// board does, so a 0 bit is alive and a 1 bit is dead.
+func readPgmPixels(r *bufio.Reader, header pgmHeader) ([]byte, error) {
+	// Rows are appended as they are read so a truncated file fails before a huge header is allocated.
+	var image []byte
+	row := make([]byte, header.width)
+	for y := 0; y < header.height; y++ {
+		if err := readPgmRow(r, header, row); err != nil {
+			return nil, fmt.Errorf("%w: row %v: %v", ErrPgmPixels, y, err)
+		}
+		image = append(image, row...)
+	}
+	return image, nil
+}
+
+// readPgmRow reads one row of pixels into row as 255/0 bytes.
+func readPgmRow(r *bufio.Reader, header pgmHeader, row []byte) error {
+	alive := func(v int) byte {
+		if 2*v > header.maxval {
+			return 255
+		}
+		return 0
+	}
+
+	switch header.magic {
+	case "P1":
+		for x := range row {
+			b, err := readPgmBit(r)
+			if err != nil {
+				return err
+			}
+			row[x] = 255 - alive(b)
+		}
+	case "P2":
+		for x := range row {
+			token, err := readPgmToken(r)
+			if err != nil {
+				return err
+			}
+			v, err := strconv.Atoi(token)
+			if err != nil || v < 0 || v > header.maxval {
+				return fmt.Errorf("grey level %q is not between 0 and %v", token, header.maxval)
+			}
+			row[x] = alive(v)
+		}
+	case "P4":
+		packed := make([]byte, (header.width+7)/8)
+		if _, err := io.ReadFull(r, packed); err != nil {
+			return err
+		}
+		for x := range row {
+			row[x] = 255 - alive(int(packed[x/8]>>(7-x%8)&1))
+		}
+	case "P5":
+		depth := 1
+		if header.maxval > 255 {
+			depth = 2
+		}
+		raw := make([]byte, header.width*depth)
+		if _, err := io.ReadFull(r, raw); err != nil {
+			return err
+		}
+		for x := range row {
+			v := int(raw[x])
+			if depth == 2 {
+				v = int(raw[2*x])<<8 | int(raw[2*x+1])
+			}
+			if v > header.maxval {
+				return fmt.Errorf("grey level %v is above the maxval %v", v, header.maxval)
+			}
+			row[x] = alive(v)
+		}
+	}
+	return nil
+}
+
+// readPgmBit reads the next 0 or 1 of a P1 bitmap, where the bits need not be separated.
+func readPgmBit(r *bufio.Reader) (int, error) {
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		switch {
+		case b == '0' || b == '1':
+			return int(b - '0'), nil
+		case b == '#':
+			if _, err := r.ReadBytes('\n'); err != nil {
+				return 0, err
+			}
+		case !isPgmSpace(b):
+			return 0, fmt.Errorf("%q is not a bit", b)
+		}
+	}
+}
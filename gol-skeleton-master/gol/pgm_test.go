@@ -0,0 +1,171 @@
+package gol
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// readPgm reads an image from data, as readPgmFile does from a file.
+func readPgm(data []byte) (pgmHeader, []byte, error) {
+	r := bufio.NewReader(bytes.NewReader(data))
+	header, err := readPgmHeader(r)
+	if err != nil {
+		return header, nil, err
+	}
+	image, err := readPgmPixels(r, header)
+	return header, image, err
+}
+
+func TestReadPgm(t *testing.T) {
+	// Every image is 3x2 with the cells alive in a diagonal stripe.
+	const expected = "\xff\x00\x00\x00\xff\x00"
+	for _, test := range []struct {
+		name, data string
+	}{
+		{"P5", "P5\n3 2\n255\n\xff\x00\x00\x00\xff\x00"},
+		{"P5 with whitespace pixels", "P5 3 2 255\n\xff\x20\x0a\x09\xff\x0d"},
+		{"P5 with comments", "P5\n# made by hand\n3 # width\n2\n# maxval next\n255\n\xff\x00\x00\x00\xff\x00"},
+		{"P5 with a small maxval", "P5\n3 2\n15\n\x0f\x07\x00\x00\x08\x01"},
+		{"P5 with a two byte maxval", "P5\n3 2\n65535\n\xff\xff\x7f\xff\x00\x00\x00\x00\x80\x00\x00\x01"},
+		{"P2", "P2\n3 2\n255\n255 0 0\n0 255 0\n"},
+		{"P2 with comments and maxval 1", "P2\n# a bitmap really\n3 2 1\n1 0 0 # first row\n0 1 0"},
+		{"P1", "P1\n3 2\n0 1 1\n1 0 1\n"},
+		{"P1 without spaces", "P1\n# comment\n3 2\n011101"},
+		{"P4", "P4\n3 2\n\x7f\xbf"},
+	} {
+		header, image, err := readPgm([]byte(test.data))
+		if err != nil {
+			t.Errorf("%v: %v", test.name, err)
+			continue
+		}
+		if header.width != 3 || header.height != 2 || string(image) != expected {
+			t.Errorf("%v: got the %vx%v image %q, expected 3x2 %q",
+				test.name, header.width, header.height, image, expected)
+		}
+	}
+
+	for _, test := range []struct {
+		data string
+		err  error
+	}{
+		{"", ErrPgmFormat},
+		{"P6\n3 2\n255\n", ErrPgmFormat},
+		{"P5\n3", ErrPgmHeader},
+		{"P5\n-3 2\n255\n", ErrPgmHeader},
+		{"P5\n3 2\n0\n", ErrPgmHeader},
+		{"P5\n3 2\n65536\n", ErrPgmHeader},
+		{"P5\n99999999 2\n255\n", ErrPgmHeader},
+		{"P5\n3 2\n255\n\xff\x00", ErrPgmPixels},
+		{"P5\n3 2\n15\n\x0f\x07\x00\x00\x10\x01", ErrPgmPixels},
+		{"P2\n3 2\n255\n255 0 0 0 256 0", ErrPgmPixels},
+		{"P2\n3 2\n255\n255 0 x 0 255 0", ErrPgmPixels},
+		{"P1\n3 2\n0 1 2 1 0 1", ErrPgmPixels},
+		{"P4\n3 2\n\x7f", ErrPgmPixels},
+	} {
+		if _, _, err := readPgm([]byte(test.data)); !errors.Is(err, test.err) {
+			t.Errorf("reading %q gave the error %v, expected %v", test.data, err, test.err)
+		}
+	}
+}
+
+// TestReadPgmFile checks that the errors for a file are PgmErrors naming it.
+func TestReadPgmFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bad.pgm")
+	if err := os.WriteFile(path, []byte("P5\n3 2\n255\n\xff"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	_, _, _, err := readPgmFile(path)
+	var pgmError *PgmError
+	if !errors.As(err, &pgmError) || pgmError.Filename != path || !errors.Is(err, ErrPgmPixels) {
+		t.Fatalf("got the error %v, expected a PgmError for %v wrapping ErrPgmPixels", err, path)
+	}
+	if !strings.HasPrefix(err.Error(), path) {
+		t.Errorf("the error %q does not start with the filename", err)
+	}
+}
+
+// FuzzReadPgm checks that any input is either read as an image of 255/0 bytes of the size
+// in its header or rejected with an error, and never panics.
+func FuzzReadPgm(f *testing.F) {
+	for _, seed := range []string{
+		"P5\n3 2\n255\n\xff\x00\x00\x00\xff\x00",
+		"P5\n# comment\n3 2\n65535\n\xff\xff\x7f\xff\x00\x00\x00\x00\x80\x00\x00\x01",
+		"P2\n3 2\n255\n255 0 0\n0 255 0\n",
+		"P1\n3 2\n011101",
+		"P4\n3 2\n\x7f\xbf",
+	} {
+		f.Add([]byte(seed))
+	}
+	if data, err := os.ReadFile("../images/16x16.pgm"); err == nil {
+		f.Add(data)
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		header, image, err := readPgm(data)
+		if err != nil {
+			if !errors.Is(err, ErrPgmFormat) && !errors.Is(err, ErrPgmHeader) && !errors.Is(err, ErrPgmPixels) {
+				t.Fatalf("the error %v is not one of the Pgm errors", err)
+			}
+			return
+		}
+		if len(image) != header.width*header.height {
+			t.Fatalf("got %v cells for a %vx%v image", len(image), header.width, header.height)
+		}
+		for _, b := range image {
+			if b != 0 && b != 255 {
+				t.Fatalf("got the cell %v, expected 0 or 255", b)
+			}
+		}
+	})
+}
+
+// FuzzPgmRoundTrip checks that a board written as a P5 image reads back the same.
+func FuzzPgmRoundTrip(f *testing.F) {
+	f.Add(uint8(3), []byte{1, 0, 0, 0, 1, 0})
+	f.Add(uint8(1), []byte{' ', '\n', '#'})
+	f.Fuzz(func(t *testing.T, width uint8, cells []byte) {
+		if width == 0 || len(cells) == 0 {
+			return
+		}
+		height := (len(cells) + int(width) - 1) / int(width)
+		board := make([]byte, int(width)*height)
+		for i, c := range cells {
+			if c&1 == 1 {
+				board[i] = 255
+			}
+		}
+
+		var b bytes.Buffer
+		b.WriteString("P5\n" + strconv.Itoa(int(width)) + " " + strconv.Itoa(height) + "\n255\n")
+		b.Write(board)
+		header, image, err := readPgm(b.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if header.width != int(width) || header.height != height || !bytes.Equal(image, board) {
+			t.Fatalf("got a %vx%v image %v, expected %vx%v %v", header.width, header.height, image, width, height, board)
+		}
+	})
+}
+
+// TestWritePgmFile checks that a board written as an image reads back the same.
+func TestWritePgmFile(t *testing.T) {
+	world := [][]byte{{255, 0, 0}, {0, 255, 255}}
+	path := filepath.Join(t.TempDir(), "board.pgm")
+	if err := writePgmFile(path, world); err != nil {
+		t.Fatal(err)
+	}
+	width, height, image, err := readPgmFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if width != 3 || height != 2 || !bytes.Equal(image, bytes.Join(world, nil)) {
+		t.Errorf("got the %vx%v image %v, expected 3x2 %v", width, height, image, world)
+	}
+}
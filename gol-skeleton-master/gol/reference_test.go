@@ -4,39 +4,10 @@ import (
 	"uk.ac.bris.cs/gameoflife/util"
 )
 
-// CPU profiling (Before)
+// The byte-per-cell kernel the distributor first computed turns with, kept as the reference the packed
+// board and the rule tables are checked against. It always wraps around the edges of the board as a torus.
 
-//func mod(a, b int) int {
-//	return (a%b + b) % b
-//}
-//
-//func calculateNeighbours(height, width int, world [][]byte, y int, x int) int {
-//
-//	h := height
-//	w := width
-//	noOfNeighbours := 0
-//
-//	neighbour := []byte{
-//		world[mod(y+1, h)][mod(x, w)],
-//		world[mod(y+1, h)][mod(x+1, w)],
-//		world[mod(y, h)][mod(x+1, w)],
-//		world[mod(y-1, h)][mod(x+1, w)],
-//		world[mod(y-1, h)][mod(x, w)],
-//		world[mod(y-1, h)][mod(x-1, w)],
-//		world[mod(y, h)][mod(x-1, w)],
-//		world[mod(y+1, h)][mod(x-1, w)],
-//	}
-//
-//	for i := 0; i < 8; i++ {
-//		if neighbour[i] == 255 {
-//			noOfNeighbours++
-//		}
-//	}
-//
-//	return noOfNeighbours
-//}
-
-// CPU profiling (After)
+// calculateNeighbours counts the alive neighbours of the cell at (x, y) in a world of 255/0 bytes.
 func calculateNeighbours(height, width int, world [][]byte, y int, x int) int {
 
 	h := height
@@ -80,10 +51,12 @@ func calculateNeighbours(height, width int, world [][]byte, y int, x int) int {
 	return noOfNeighbours
 }
 
+// calculateNextState returns rows startY to endY of the world after a turn of Conway's rules,
+// with the cells that flipped in them.
 func calculateNextState(height, width, startY, endY int, world [][]byte) ([][]byte, []util.Cell) {
 
 	newWorld := make([][]byte, endY-startY)
-	flipCell := make([]util.Cell, height, width)
+	var flipCell []util.Cell
 	for i := 0; i < endY-startY; i++ {
 		newWorld[i] = make([]byte, len(world[0]))
 	}
@@ -110,18 +83,3 @@ func calculateNextState(height, width, startY, endY int, world [][]byte) ([][]by
 
 	return newWorld, flipCell
 }
-
-func calculateAliveCells(p Params, world [][]byte) (int, []util.Cell) {
-
-	var aliveCells []util.Cell
-	count := 0
-	for y := 0; y < p.ImageHeight; y++ {
-		for x := 0; x < p.ImageWidth; x++ {
-			if world[y][x] == 255 {
-				count++
-				aliveCells = append(aliveCells, util.Cell{X: x, Y: y})
-			}
-		}
-	}
-	return count, aliveCells
-}
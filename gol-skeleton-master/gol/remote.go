@@ -0,0 +1,213 @@
+package gol
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/rpc"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// errFinishedEarly is the error a BrokerError wraps when the broker ends the run before the controller does.
+var errFinishedEarly = errors.New("the run finished early")
+
+// BrokerError is returned when the broker at Address cannot be reached or fails part way through a run.
+type BrokerError struct {
+	Address string
+	Err     error
+}
+
+func (e *BrokerError) Error() string {
+	return fmt.Sprintf("broker %v: %v", e.Address, e.Err)
+}
+
+func (e *BrokerError) Unwrap() error {
+	return e.Err
+}
+
+// controllerTimeout is how long the controller waits for the broker to answer a call. It is longer than
+// callTimeout, as the broker may have to give up on its workers and compute a turn itself before it answers.
+const controllerTimeout = 4 * callTimeout
+
+// remoteEngine runs the simulation on a broker and replays the flipped cells it reports
+// onto a local copy of the board, so saving and counting never need a round trip.
+type remoteEngine struct {
+	address string
+	// ctx cuts short the calls made while the run is going, but not those that wind it up.
+	ctx     context.Context
+	client  *rpc.Client
+	events  chan<- Event
+	updates chan TurnUpdate
+	quit    chan struct{}
+	spare   *board
+	// failed is why poll gave up, set before updates is closed.
+	failed error
+}
+
+// dialBroker connects to the broker at p.Broker. Workers joining or leaving the run are reported on events.
+// Calls to the broker give up when ctx is done.
+func dialBroker(ctx context.Context, p Params, events chan<- Event) (*remoteEngine, error) {
+	dialer := net.Dialer{Timeout: callTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", p.Broker)
+	if err != nil {
+		return nil, &BrokerError{p.Broker, err}
+	}
+	return &remoteEngine{
+		address: p.Broker,
+		ctx:     ctx,
+		client:  rpc.NewClient(conn),
+		events:  events,
+		updates: make(chan TurnUpdate, updateQueueLength),
+		quit:    make(chan struct{}),
+	}, nil
+}
+
+// call makes the RPC call method to the broker, giving up with errTimeout after controllerTimeout or
+// with the error of ctx once it is done. Any error is wrapped in a BrokerError, and reply must not be
+// used after one, as the call may still be writing to it.
+func (r *remoteEngine) call(ctx context.Context, method string, args, reply interface{}) error {
+	timer := time.NewTimer(controllerTimeout)
+	defer timer.Stop()
+	call := r.client.Go(method, args, reply, make(chan *rpc.Call, 1))
+	var err error
+	select {
+	case <-call.Done:
+		err = call.Error
+	case <-timer.C:
+		err = errTimeout
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	if err != nil {
+		return &BrokerError{r.address, err}
+	}
+	return nil
+}
+
+// attach follows the run already in progress on the broker, if there is one.
+// It returns the run's parameters, its board and the number of turns the board has been through.
+func (r *remoteEngine) attach() (Params, *board, int, bool, error) {
+	var reply AttachReply
+	if err := r.call(r.ctx, BrokerAttach, AttachArgs{}, &reply); err != nil {
+		return Params{}, nil, 0, false, err
+	}
+	if !reply.Attached {
+		return Params{}, nil, 0, false, nil
+	}
+	p := reply.Params
+	go r.poll()
+	return p, boardFromWords(p.ImageWidth, p.ImageHeight, p.Boundary, reply.Words), reply.CompletedTurns, true, nil
+}
+
+// start sends world, the board after turn turns, to the broker and starts a new run there.
+func (r *remoteEngine) start(p Params, world *board, turn int) error {
+	err := r.call(r.ctx, BrokerStart, StartArgs{Params: p, Words: world.words, CompletedTurns: turn}, &StartReply{})
+	if err != nil {
+		return err
+	}
+	go r.poll()
+	return nil
+}
+
+// poll collects turns from the broker until the run is done or the engine is stopped.
+// It stops asking once updates is full, which in turn makes the broker wait.
+// If the broker cannot be reached it records the error in failed and closes updates.
+func (r *remoteEngine) poll() {
+	for {
+		var reply UpdatesReply
+		err := r.call(r.ctx, BrokerUpdates, UpdatesArgs{}, &reply)
+		select {
+		case <-r.quit:
+			return
+		default:
+		}
+		if err != nil {
+			r.failed = err
+			close(r.updates)
+			return
+		}
+
+		for _, update := range reply.Turns {
+			select {
+			case r.updates <- update:
+			case <-r.quit:
+				return
+			}
+		}
+		if reply.Done {
+			close(r.updates)
+			return
+		}
+	}
+}
+
+// step waits for the broker's next turn and applies it to a copy of world.
+func (r *remoteEngine) step(world *board) (*board, []util.Cell, error) {
+	var update TurnUpdate
+	var ok bool
+	select {
+	case update, ok = <-r.updates:
+	case <-r.ctx.Done():
+		return nil, nil, &BrokerError{r.address, r.ctx.Err()}
+	}
+	if !ok {
+		if r.failed != nil {
+			return nil, nil, r.failed
+		}
+		return nil, nil, &BrokerError{r.address, errFinishedEarly}
+	}
+	for _, address := range update.Joined {
+		r.events <- WorkerJoined{CompletedTurns: update.CompletedTurns, Address: address}
+	}
+	for _, address := range update.Lost {
+		r.events <- WorkerLost{CompletedTurns: update.CompletedTurns, Address: address}
+	}
+	if update.Rebalanced {
+		r.events <- MembershipChanged{CompletedTurns: update.CompletedTurns, Strips: update.Strips}
+	}
+
+	next := r.spare
+	if next == nil {
+		next = newBoard(world.width, world.height, world.boundary)
+	}
+	copy(next.words, world.words)
+	for _, cell := range update.Flipped {
+		next.set(cell.X, cell.Y, !next.get(cell.X, cell.Y))
+	}
+	r.spare = world
+	return next, update.Flipped, nil
+}
+
+// end stops polling, makes the RPC call method to wind the run up and closes the connection.
+func (r *remoteEngine) end(method string, args, reply interface{}) error {
+	close(r.quit)
+	err := r.call(context.Background(), method, args, reply)
+	if closeErr := r.client.Close(); err == nil && closeErr != nil {
+		err = &BrokerError{r.address, closeErr}
+	}
+	return err
+}
+
+// stop ends the run on the broker.
+func (r *remoteEngine) stop() error {
+	return r.end(BrokerStop, StopArgs{}, &StopReply{})
+}
+
+// detach leaves the run on the broker, which carries on computing turns for a later controller.
+func (r *remoteEngine) detach() error {
+	return r.end(BrokerDetach, DetachArgs{}, &DetachReply{})
+}
+
+// shutdown ends the run and tells the broker to exit, taking its workers with it.
+func (r *remoteEngine) shutdown() error {
+	return r.end(BrokerShutdown, ShutdownArgs{}, &ShutdownReply{})
+}
+
+// disconnect drops the connection to the broker without winding the run up, for when the controller gives up on it.
+func (r *remoteEngine) disconnect() {
+	close(r.quit)
+	r.client.Close()
+}
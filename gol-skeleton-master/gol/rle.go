@@ -0,0 +1,179 @@
+package gol
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// rleLineLength is the longest line written to an RLE file, as the format asks.
+const rleLineLength = 70
+
+// readRLE reads a pattern in the run length encoded format. After any # comment lines comes
+// a header line "x = <width>, y = <height>, rule = <rulestring>", where the rule is optional,
+// followed by runs of b (dead) and o (alive) cells. $ ends a row and ! ends the pattern.
+// A run is a tag with an optional count in front of it, so "3o$" is three live cells and a new row.
+func readRLE(r io.Reader) (*pattern, error) {
+	scanner := bufio.NewScanner(r)
+	var p *pattern
+	x, y, count, digits := 0, 0, 0, false
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if p == nil {
+			var err error
+			if p, err = parseRLEHeader(line); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		for _, c := range line {
+			switch {
+			case c >= '0' && c <= '9':
+				// Runs are capped at the largest board, which also keeps the count from overflowing.
+				count = count*10 + int(c-'0')
+				digits = true
+				if count > maxPgmSize {
+					return nil, fmt.Errorf("rle: a run is longer than %v cells", maxPgmSize)
+				}
+				continue
+			case c == ' ' || c == '\t':
+				continue
+			}
+			run := count
+			if !digits {
+				run = 1
+			} else if run == 0 {
+				return nil, errors.New("rle: a run of 0 cells")
+			}
+			count, digits = 0, false
+
+			switch c {
+			case 'b', '.':
+				x += run
+			case 'o', 'A':
+				if x+run > p.width || y >= p.height {
+					return nil, fmt.Errorf("rle: cells run outside the %vx%v bounding box", p.width, p.height)
+				}
+				for i := 0; i < run; i++ {
+					p.alive = append(p.alive, util.Cell{X: x + i, Y: y})
+				}
+				x += run
+			case '$':
+				x = 0
+				y += run
+			case '!':
+				return p, nil
+			default:
+				return nil, fmt.Errorf("rle: unexpected %q in the pattern", c)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return nil, errors.New("rle: no header line")
+	}
+	return nil, errors.New("rle: pattern does not end with '!'")
+}
+
+// parseRLEHeader parses a header line such as "x = 3, y = 3, rule = B3/S23".
+func parseRLEHeader(line string) (*pattern, error) {
+	p := &pattern{}
+	fields := strings.Split(line, ",")
+	// The rule comes last and may have commas of its own.
+	for i, field := range fields {
+		if strings.TrimSpace(strings.SplitN(field, "=", 2)[0]) == "rule" {
+			fields = append(fields[:i], strings.Join(fields[i:], ","))
+			break
+		}
+	}
+	for _, field := range fields {
+		pair := strings.SplitN(field, "=", 2)
+		if len(pair) != 2 {
+			return nil, fmt.Errorf("rle: header %q: expected key = value pairs", line)
+		}
+		key, value := strings.TrimSpace(pair[0]), strings.TrimSpace(pair[1])
+		var err error
+		switch key {
+		case "x":
+			p.width, err = strconv.Atoi(value)
+		case "y":
+			p.height, err = strconv.Atoi(value)
+		case "rule":
+			// Golly adds the shape of a bounded grid after a colon, which the board size stands in for here.
+			p.rule, err = ParseRule(strings.SplitN(value, ":", 2)[0])
+		}
+		if err != nil {
+			return nil, fmt.Errorf("rle: header %q: %w", line, err)
+		}
+	}
+	if p.width <= 0 || p.height <= 0 {
+		return nil, fmt.Errorf("rle: header %q: x and y must be positive", line)
+	}
+	if p.width > maxPgmSize || p.height > maxPgmSize {
+		return nil, fmt.Errorf("rle: header %q: x and y must be at most %v", line, maxPgmSize)
+	}
+	return p, nil
+}
+
+// writeRLE writes world, a grid of 255/0 bytes, in the run length encoded format.
+// Dead cells at the end of a row and empty rows at the bottom are left out, as the header gives the size.
+func writeRLE(w io.Writer, world [][]byte, rule Rule) error {
+	width := 0
+	if len(world) > 0 {
+		width = len(world[0])
+	}
+	out := bufio.NewWriter(w)
+	fmt.Fprintf(out, "x = %v, y = %v, rule = %v\n", width, len(world), rule)
+
+	line := 0
+	token := func(run int, tag byte) {
+		s := string(tag)
+		if run > 1 {
+			s = strconv.Itoa(run) + s
+		}
+		if line+len(s) > rleLineLength {
+			out.WriteByte('\n')
+			line = 0
+		}
+		out.WriteString(s)
+		line += len(s)
+	}
+
+	rowEnds := 0
+	for _, row := range world {
+		for x := 0; x < len(row); {
+			run := 1
+			for x+run < len(row) && row[x+run] == row[x] {
+				run++
+			}
+			// A dead run at the end of the row is left to the row end.
+			if row[x] == 255 || x+run < len(row) {
+				if rowEnds > 0 {
+					token(rowEnds, '$')
+					rowEnds = 0
+				}
+				tag := byte('b')
+				if row[x] == 255 {
+					tag = 'o'
+				}
+				token(run, tag)
+			}
+			x += run
+		}
+		rowEnds++
+	}
+	token(1, '!')
+	out.WriteByte('\n')
+	return out.Flush()
+}
@@ -0,0 +1,115 @@
+package gol
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestReadRLE(t *testing.T) {
+	glider := "#N Glider\n#C A comment\nx = 3, y = 3, rule = B3/S23\nbob$2bo$3o!\n"
+	p, err := readRLE(strings.NewReader(glider))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.width != 3 || p.height != 3 || p.rule != conway {
+		t.Fatalf("got a %vx%v pattern with rule %v, expected 3x3 with B3/S23", p.width, p.height, p.rule)
+	}
+	if fmt.Sprint(p.alive) != "[{1 0} {2 1} {0 2} {1 2} {2 2}]" {
+		t.Fatalf("got the cells %v", p.alive)
+	}
+
+	highLife, err := readRLE(strings.NewReader("x = 1, y = 2, rule = B36/S23:T10,10\no\n$!"))
+	if err != nil || highLife.rule.String() != "B36/S23" || len(highLife.alive) != 1 {
+		t.Fatalf("got %+v, %v for a HighLife pattern split over lines", highLife, err)
+	}
+
+	for _, bad := range []string{
+		"",
+		"bo$!",
+		"x = 0, y = 3\n!",
+		"x = 3, y = 3, rule = B9/S\nbo!",
+		"x = 2, y = 2\n3o!",
+		"x = 2, y = 2\n$$o!",
+		"x = 2, y = 2\nbz!",
+		"x = 2, y = 2\nbo",
+		// Counts and sizes that are too big, or that overflow, are refused rather than trusted.
+		"x = 2, y = 2\n0o!",
+		"x = 2, y = 2\n99999999999999999999b2o!",
+		"x = 2, y = 2\n18446744073709551615$o!",
+		"x = 65537, y = 1\no!",
+		"x = 1, y = 99999999999999999999\no!",
+		"x = -5, y = 2\no!",
+	} {
+		if _, err := readRLE(strings.NewReader(bad)); err == nil {
+			t.Errorf("expected an error reading %q", bad)
+		}
+	}
+}
+
+// TestRLERoundTrip checks that writing a board as RLE and reading it back gives the same board,
+// including rows long enough to be wrapped and empty rows in the middle and at the bottom.
+func TestRLERoundTrip(t *testing.T) {
+	for _, size := range [][2]int{{16, 16}, {100, 7}, {1, 1}, {200, 50}} {
+		width, height := size[0], size[1]
+		world := randomWorld(width, height, int64(width))
+		for x := range world[height/2] {
+			world[height/2][x] = 0
+			world[height-1][x] = 0
+		}
+
+		var b bytes.Buffer
+		if err := writeRLE(&b, world, Rule{}); err != nil {
+			t.Fatal(err)
+		}
+		for _, line := range strings.Split(b.String(), "\n") {
+			if len(line) > rleLineLength {
+				t.Fatalf("%dx%d: line of %v characters", width, height, len(line))
+			}
+		}
+		p, err := readRLE(&b)
+		if err != nil {
+			t.Fatalf("%dx%d: %v", width, height, err)
+		}
+		got, err := p.centre(width, height)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(bytes.Join(got, nil), bytes.Join(world, nil)) {
+			t.Fatalf("%dx%d: the board read back differs from the one written", width, height)
+		}
+	}
+}
+
+// FuzzReadRLE checks that a pattern readRLE accepts has a size a board can be, with every cell inside it.
+func FuzzReadRLE(f *testing.F) {
+	for _, seed := range []string{
+		"x = 3, y = 3\nbo$2bo$3o!",
+		"#N Glider\nx = 3, y = 3, rule = B36/S23\nbob$2bo$3o!",
+		"x = 2, y = 2\n99999b2o!",
+		"x = 65536, y = 1\n65536o!",
+	} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, data string) {
+		p, err := readRLE(strings.NewReader(data))
+		if err != nil {
+			return
+		}
+		if p.width <= 0 || p.height <= 0 || p.width > maxPgmSize || p.height > maxPgmSize {
+			t.Fatalf("got a %vx%v pattern", p.width, p.height)
+		}
+		for _, cell := range p.alive {
+			if cell.X < 0 || cell.Y < 0 || cell.X >= p.width || cell.Y >= p.height {
+				t.Fatalf("the cell %v is outside the %vx%v pattern", cell, p.width, p.height)
+			}
+		}
+		if p.width*p.height <= 1<<20 {
+			if _, err := p.centre(p.width, p.height); err != nil {
+				t.Fatalf("the pattern does not fit on a board of its own size: %v", err)
+			}
+		}
+	})
+}
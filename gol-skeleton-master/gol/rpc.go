@@ -0,0 +1,208 @@
+package gol
+
+import (
+	"errors"
+	"net"
+	"net/rpc"
+	"sync"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// RPC method names served by the broker and the worker nodes.
+const (
+	BrokerRegister   = "Broker.Register"
+	BrokerDeregister = "Broker.Deregister"
+	BrokerStart      = "Broker.Start"
+	BrokerUpdates    = "Broker.Updates"
+	BrokerAttach     = "Broker.Attach"
+	BrokerDetach     = "Broker.Detach"
+	BrokerStop       = "Broker.Stop"
+	BrokerShutdown   = "Broker.Shutdown"
+
+	WorkerStep     = "Worker.Step"
+	WorkerLoad     = "Worker.Load"
+	WorkerAdvance  = "Worker.Advance"
+	WorkerEdge     = "Worker.Edge"
+	WorkerShutdown = "Worker.Shutdown"
+	WorkerPing     = "Worker.Ping"
+)
+
+const (
+	// callTimeout is how long the broker and the workers wait for each other before giving up.
+	callTimeout = 5 * time.Second
+	// heartbeatInterval is how often the broker checks that its workers are still there.
+	heartbeatInterval = time.Second
+)
+
+var errTimeout = errors.New("call timed out")
+
+// RegisterArgs is sent by a worker node to join a broker.
+type RegisterArgs struct {
+	// Address is where the broker can reach the worker's RPC server.
+	Address string
+}
+
+type RegisterReply struct{}
+
+// DeregisterArgs is sent by a worker node to leave a broker.
+type DeregisterArgs struct {
+	Address string
+}
+
+type DeregisterReply struct{}
+
+// StartArgs hands a loaded world to the broker and starts a run.
+type StartArgs struct {
+	Params Params
+	// Words is the bit-packed board, ghost rows included.
+	Words []uint64
+	// CompletedTurns is the number of turns the board has been through, for a run resumed from a checkpoint.
+	CompletedTurns int
+}
+
+type StartReply struct{}
+
+// TurnUpdate carries the cells that flipped in one turn,
+// along with the addresses of any workers that joined or were lost since the turn before.
+// Rebalanced is set if the rows were shared out again for the turn, as described by Strips.
+type TurnUpdate struct {
+	// CompletedTurns is the number of turns completed before the cells flipped.
+	CompletedTurns int
+	Flipped        []util.Cell
+	Joined, Lost   []string
+	Rebalanced     bool
+	Strips         []WorkerStrip
+}
+
+type UpdatesArgs struct{}
+
+// UpdatesReply carries every turn completed since the last call.
+// Done is set once the run has finished and no more updates will follow.
+type UpdatesReply struct {
+	Turns []TurnUpdate
+	Done  bool
+}
+
+type AttachArgs struct{}
+
+// AttachReply describes the run the controller attached to.
+// Attached is false if the broker has no run in progress.
+type AttachReply struct {
+	Attached bool
+	Params   Params
+	// CompletedTurns is the number of turns Words has been through.
+	CompletedTurns int
+	Words          []uint64
+}
+
+type DetachArgs struct{}
+
+type DetachReply struct{}
+
+type StopArgs struct{}
+
+type StopReply struct{}
+
+type PingArgs struct{}
+
+type PingReply struct{}
+
+// ShutdownArgs asks the broker or a worker node to stop serving and exit.
+type ShutdownArgs struct{}
+
+type ShutdownReply struct{}
+
+// StepArgs asks a worker node to compute one turn of rows [StartY, EndY).
+type StepArgs struct {
+	Width        int
+	StartY, EndY int
+	Rule         Rule
+	// Rows is the bit-packed rows StartY-1 to EndY inclusive, with their ghost cells filled in.
+	Rows []uint64
+}
+
+// StepReply carries the bit-packed rows [StartY, EndY) after the turn and the cells that flipped.
+// Elapsed is how long the worker took to compute them.
+type StepReply struct {
+	Rows    []uint64
+	Flipped []util.Cell
+	Elapsed time.Duration
+}
+
+// LoadArgs hands a worker node a strip of the board to own for a halo-exchange run.
+type LoadArgs struct {
+	Width, Height int
+	Boundary      Boundary
+	Rule          Rule
+	StartY, EndY  int
+	// CompletedTurns is the number of turns the rows have already been through.
+	CompletedTurns int
+	// Rows is the bit-packed rows [StartY, EndY) with their ghost cells filled in.
+	Rows []uint64
+	// Above and Below are the addresses of the workers owning the strips either side of this one.
+	Above, Below string
+}
+
+type LoadReply struct{}
+
+// AdvanceArgs asks a worker node to move its strip on by one turn.
+type AdvanceArgs struct {
+	CompletedTurns int
+}
+
+// AdvanceReply carries the cells of the strip that flipped and how long the worker took to compute them,
+// not counting the wait for its neighbours' edge rows.
+type AdvanceReply struct {
+	Flipped []util.Cell
+	Elapsed time.Duration
+}
+
+// EdgeArgs asks a worker node for the top or bottom row of its strip after CompletedTurns turns.
+type EdgeArgs struct {
+	CompletedTurns int
+	Bottom         bool
+}
+
+type EdgeReply struct {
+	Row []uint64
+}
+
+// serve answers calls on every connection accepted by listener. Once the listener is closed
+// it waits for the open connections to be closed by their clients, so the reply to a
+// shutdown call always reaches the caller before the process exits.
+func serve(server *rpc.Server, listener net.Listener) {
+	var connections sync.WaitGroup
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			break
+		}
+		connections.Add(1)
+		go func() {
+			server.ServeConn(conn)
+			connections.Done()
+		}()
+	}
+	connections.Wait()
+}
+
+// wait waits for call to complete, giving up with errTimeout after callTimeout.
+func wait(call *rpc.Call) error {
+	timer := time.NewTimer(callTimeout)
+	defer timer.Stop()
+	select {
+	case <-call.Done:
+		return call.Error
+	case <-timer.C:
+		return errTimeout
+	}
+}
+
+// unreachable reports whether err from a call means the other end could not be reached,
+// rather than that it answered with an error of its own.
+func unreachable(err error) bool {
+	var serverErr rpc.ServerError
+	return !errors.As(err, &serverErr)
+}
@@ -0,0 +1,149 @@
+package gol
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Rule is an outer-totalistic rule on the Moore neighbourhood.
+// Bit n of Birth is set if a dead cell with n live neighbours comes alive,
+// and bit n of Survive is set if a live cell with n live neighbours stays alive.
+// The zero Rule stands for Conway's Life, B3/S23.
+type Rule struct {
+	Birth, Survive uint16
+}
+
+var conway = Rule{Birth: 1 << 3, Survive: 1<<2 | 1<<3}
+
+// ParseRule parses a rulestring in B/S notation such as "B3/S23", "B36/S23" or "B2/S",
+// or in the older survival/birth notation such as "23/3".
+func ParseRule(s string) (Rule, error) {
+	parts := strings.Split(strings.TrimSpace(s), "/")
+	if len(parts) != 2 {
+		return Rule{}, fmt.Errorf("rule %q: expected two parts separated by '/'", s)
+	}
+
+	var rule Rule
+	var err error
+	first, second := strings.ToUpper(parts[0]), strings.ToUpper(parts[1])
+	switch {
+	case strings.HasPrefix(first, "B") && strings.HasPrefix(second, "S"):
+		rule.Birth, err = parseCounts(first[1:])
+		if err == nil {
+			rule.Survive, err = parseCounts(second[1:])
+		}
+	case strings.HasPrefix(first, "S") && strings.HasPrefix(second, "B"):
+		rule.Survive, err = parseCounts(first[1:])
+		if err == nil {
+			rule.Birth, err = parseCounts(second[1:])
+		}
+	default:
+		rule.Survive, err = parseCounts(first)
+		if err == nil {
+			rule.Birth, err = parseCounts(second)
+		}
+	}
+	if err != nil {
+		return Rule{}, fmt.Errorf("rule %q: %w", s, err)
+	}
+	if rule == (Rule{}) {
+		return Rule{}, fmt.Errorf("rule %q: no cell is ever born or survives", s)
+	}
+	return rule, nil
+}
+
+// parseCounts turns a list of neighbour counts such as "236" into a bit set.
+func parseCounts(s string) (uint16, error) {
+	var counts uint16
+	for _, r := range s {
+		if r < '0' || r > '8' {
+			return 0, errors.New("neighbour counts must be digits from 0 to 8")
+		}
+		counts |= 1 << (r - '0')
+	}
+	return counts, nil
+}
+
+func (rule Rule) orConway() Rule {
+	if rule == (Rule{}) {
+		return conway
+	}
+	return rule
+}
+
+// String returns the rule in B/S notation.
+func (rule Rule) String() string {
+	rule = rule.orConway()
+	return "B" + formatCounts(rule.Birth) + "/S" + formatCounts(rule.Survive)
+}
+
+func formatCounts(counts uint16) string {
+	var b strings.Builder
+	for n := 0; n <= 8; n++ {
+		if counts>>n&1 == 1 {
+			b.WriteByte(byte('0' + n))
+		}
+	}
+	return b.String()
+}
+
+// ruleTable is a Rule compiled into the lookup tables used by the kernels.
+type ruleTable struct {
+	// next[alive][n] says whether a cell with n live neighbours is alive next turn.
+	next [2][9]bool
+	// lut[alive<<3|n] is next[alive][n] for n < 8, widened to an all-ones or all-zeros word.
+	lut [16]uint64
+	// eight[alive] is next[alive][8] widened the same way.
+	eight [2]uint64
+}
+
+func newRuleTable(rule Rule) *ruleTable {
+	rule = rule.orConway()
+	t := &ruleTable{}
+	for n := 0; n <= 8; n++ {
+		t.next[0][n] = rule.Birth>>n&1 == 1
+		t.next[1][n] = rule.Survive>>n&1 == 1
+	}
+	for alive := 0; alive < 2; alive++ {
+		for n := 0; n < 8; n++ {
+			t.lut[alive<<3|n] = widen(t.next[alive][n])
+		}
+		t.eight[alive] = widen(t.next[alive][8])
+	}
+	return t
+}
+
+func widen(b bool) uint64 {
+	if b {
+		return ^uint64(0)
+	}
+	return 0
+}
+
+// mux picks the bits of one where sel is set and the bits of zero elsewhere.
+func mux(sel, one, zero uint64) uint64 {
+	return zero ^ (sel & (zero ^ one))
+}
+
+// apply looks up the next state of 64 cells at once. c holds the cells and s0..s3 the bit
+// planes of their neighbour counts. Every bit walks the same tree of multiplexers down the
+// table whatever the rule is, so all rules cost the same.
+func (t *ruleTable) apply(c, s0, s1, s2, s3 uint64) uint64 {
+	var byS0 [8]uint64
+	for i := range byS0 {
+		byS0[i] = mux(s0, t.lut[2*i+1], t.lut[2*i])
+	}
+	var byS1 [4]uint64
+	for i := range byS1 {
+		byS1[i] = mux(s1, byS0[2*i+1], byS0[2*i])
+	}
+	byS2 := [2]uint64{
+		mux(s2, byS1[1], byS1[0]),
+		mux(s2, byS1[3], byS1[2]),
+	}
+	// s3 is only set for a count of 8, when s0..s2 are all clear.
+	belowEight := mux(c, byS2[1], byS2[0])
+	eight := mux(c, t.eight[1], t.eight[0])
+	return mux(s3, eight, belowEight)
+}
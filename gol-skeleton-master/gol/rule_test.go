@@ -0,0 +1,87 @@
+package gol
+
+import (
+	"testing"
+)
+
+func TestParseRule(t *testing.T) {
+	tests := []struct {
+		rulestring string
+		expected   string
+	}{
+		{"B3/S23", "B3/S23"},
+		{"b3/s23", "B3/S23"},
+		{"B36/S23", "B36/S23"},
+		{"B2/S", "B2/S"},
+		{"23/3", "B3/S23"},
+		{"S23/B36", "B36/S23"},
+		{"B3678/S34678", "B3678/S34678"},
+	}
+	for _, test := range tests {
+		rule, err := ParseRule(test.rulestring)
+		if err != nil {
+			t.Errorf("ParseRule(%q) returned error %v", test.rulestring, err)
+			continue
+		}
+		if rule.String() != test.expected {
+			t.Errorf("ParseRule(%q) = %v, expected %v", test.rulestring, rule, test.expected)
+		}
+	}
+
+	for _, bad := range []string{"", "B3", "B9/S23", "B3/S2x", "B/S", "B3/S23/C2"} {
+		if _, err := ParseRule(bad); err == nil {
+			t.Errorf("ParseRule(%q) should have returned an error", bad)
+		}
+	}
+
+	if (Rule{}).String() != "B3/S23" {
+		t.Errorf("the zero Rule should be Conway's Life, got %v", Rule{})
+	}
+}
+
+// TestRuleKernel runs a few rules through the packed kernel and checks every turn
+// against a direct lookup in the rule table.
+func TestRuleKernel(t *testing.T) {
+	for _, rulestring := range []string{"B3/S23", "B36/S23", "B2/S", "B3678/S34678", "B0123478/S01234678"} {
+		rule, err := ParseRule(rulestring)
+		if err != nil {
+			t.Fatal(err)
+		}
+		table := newRuleTable(rule)
+		width, height := 70, 20
+		world := randomWorld(width, height, 7)
+		packed := newBoard(width, height, Torus)
+		packed.fromBytes(world)
+
+		for turn := 0; turn < 10; turn++ {
+			expected := make([][]byte, height)
+			for y := range expected {
+				expected[y] = make([]byte, width)
+				for x := range expected[y] {
+					alive := 0
+					if world[y][x] == 255 {
+						alive = 1
+					}
+					if table.next[alive][calculateNeighbours(height, width, world, y, x)] {
+						expected[y][x] = 255
+					}
+				}
+			}
+			world = expected
+
+			next := newBoard(width, height, Torus)
+			packed.step(next, table, 0, height, nil)
+			next.fillGhosts()
+			packed = next
+
+			actual := packed.toBytes()
+			for y := range world {
+				for x := range world[y] {
+					if world[y][x] != actual[y][x] {
+						t.Fatalf("%v turn %d: cell (%d, %d) should be %d, got %d", rule, turn+1, x, y, world[y][x], actual[y][x])
+					}
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package gol
+
+import "time"
+
+// speedSteps are the caps on turns per second that '+' and '-' step through.
+// '+' on the last step lifts the cap, and '-' on an uncapped run goes back to the last step.
+var speedSteps = []float64{1, 2, 5, 10, 20, 50, 100, 200, 500, 1000, 2000, 5000}
+
+// faster returns the step above tps, or 0 for no cap.
+func faster(tps float64) float64 {
+	if tps <= 0 {
+		return 0
+	}
+	for _, step := range speedSteps {
+		if step > tps {
+			return step
+		}
+	}
+	return 0
+}
+
+// slower returns the step below tps, which stays at the first step.
+func slower(tps float64) float64 {
+	if tps <= 0 {
+		return speedSteps[len(speedSteps)-1]
+	}
+	for i := len(speedSteps) - 1; i >= 0; i-- {
+		if speedSteps[i] < tps {
+			return speedSteps[i]
+		}
+	}
+	return speedSteps[0]
+}
+
+// immediately is always ready, for when the next turn can start straight away.
+var immediately = func() <-chan time.Time {
+	c := make(chan time.Time)
+	close(c)
+	return c
+}()
+
+// limiter spaces turns out to at most tps a second, or not at all if tps is 0.
+// It keeps one timer, reset on each wait, so a capped run does not make a new one every pass.
+type limiter struct {
+	tps   float64
+	next  time.Time
+	timer *time.Timer
+}
+
+// set changes the cap to tps turns a second, starting from now.
+func (l *limiter) set(tps float64) {
+	l.tps = tps
+	l.next = time.Now()
+}
+
+// ready returns a channel that is ready once the next turn may start,
+// so the distributor can wait for it alongside key presses.
+func (l *limiter) ready() <-chan time.Time {
+	if l.tps <= 0 {
+		return immediately
+	}
+	wait := time.Until(l.next)
+	if wait <= 0 {
+		return immediately
+	}
+	if l.timer == nil {
+		l.timer = time.NewTimer(wait)
+		return l.timer.C
+	}
+	// A timer that fired without being waited for still holds its time, which would end the wait early.
+	if !l.timer.Stop() {
+		select {
+		case <-l.timer.C:
+		default:
+		}
+	}
+	l.timer.Reset(wait)
+	return l.timer.C
+}
+
+// stop releases the timer.
+func (l *limiter) stop() {
+	if l.timer != nil {
+		l.timer.Stop()
+	}
+}
+
+// took records that a turn has started. A turn started late, say after a pause,
+// does not let the turns after it catch up.
+func (l *limiter) took() {
+	if l.tps <= 0 {
+		return
+	}
+	start := time.Now()
+	if l.next.After(start) {
+		start = l.next
+	}
+	l.next = start.Add(time.Duration(float64(time.Second) / l.tps))
+}
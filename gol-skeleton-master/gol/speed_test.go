@@ -0,0 +1,63 @@
+package gol
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSpeedSteps(t *testing.T) {
+	for _, test := range []struct {
+		tps, faster, slower float64
+	}{
+		{0, 0, 5000},
+		{1, 2, 1},
+		{2, 5, 1},
+		{3, 5, 2},
+		{50, 100, 20},
+		{5000, 0, 2000},
+		{9000, 0, 5000},
+	} {
+		if got := faster(test.tps); got != test.faster {
+			t.Errorf("faster(%v) = %v, expected %v", test.tps, got, test.faster)
+		}
+		if got := slower(test.tps); got != test.slower {
+			t.Errorf("slower(%v) = %v, expected %v", test.tps, got, test.slower)
+		}
+	}
+}
+
+// TestLimiter checks that the limiter is ready at once without a cap, and spaces the turns out with one.
+func TestLimiter(t *testing.T) {
+	var l limiter
+	l.set(0)
+	for i := 0; i < 100; i++ {
+		l.took()
+		select {
+		case <-l.ready():
+		default:
+			t.Fatal("an uncapped limiter is not ready")
+		}
+	}
+
+	l.set(100)
+	start := time.Now()
+	for i := 0; i < 10; i++ {
+		<-l.ready()
+		l.took()
+	}
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Errorf("10 turns at 100 turns/sec took %v, expected at least 90ms", elapsed)
+	}
+	// Waiting again and again for the same turn reuses the timer.
+	l.set(1)
+	l.took()
+	if allocs := testing.AllocsPerRun(100, func() { l.ready() }); allocs != 0 {
+		t.Errorf("expected no allocations waiting for a capped turn, got %v", allocs)
+	}
+	select {
+	case <-l.ready():
+		t.Error("a limiter capped at 1 turn/sec was ready straight after a turn")
+	default:
+	}
+	l.stop()
+}
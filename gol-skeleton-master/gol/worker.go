@@ -0,0 +1,153 @@
+package gol
+
+import (
+	"math"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// strip is the half-open range of rows [startY, endY) owned by one worker.
+type strip struct {
+	startY, endY int
+}
+
+// splitRows divides height rows into n horizontal strips.
+// When height is not divisible by n the first height%n strips get one extra row.
+func splitRows(height, n int) []strip {
+	if n > height {
+		n = height
+	}
+	if n < 1 {
+		n = 1
+	}
+	strips := make([]strip, n)
+	base := height / n
+	extra := height % n
+	startY := 0
+	for i := range strips {
+		rows := base
+		if i < extra {
+			rows++
+		}
+		strips[i] = strip{startY: startY, endY: startY + rows}
+		startY += rows
+	}
+	return strips
+}
+
+// splitWeighted divides height rows into one strip per weight, with the rows shared out in proportion
+// to the weights. Every strip gets at least one row, so weights past the height-th are left out.
+func splitWeighted(height int, weights []float64) []strip {
+	n := len(weights)
+	if n > height {
+		n = height
+	}
+	var total float64
+	for _, weight := range weights[:n] {
+		total += weight
+	}
+	strips := make([]strip, n)
+	startY := 0
+	var cumulative float64
+	for i := range strips {
+		cumulative += weights[i]
+		endY := int(math.Round(float64(height) * cumulative / total))
+		if endY < startY+1 {
+			endY = startY + 1
+		}
+		if endY > height-(n-1-i) {
+			endY = height - (n - 1 - i)
+		}
+		strips[i] = strip{startY: startY, endY: endY}
+		startY = endY
+	}
+	return strips
+}
+
+// stripJob asks a worker to compute its strip of next from current.
+type stripJob struct {
+	current, next *board
+	rule          *ruleTable
+}
+
+// worker computes the next state of its strip every time a job is sent on jobs
+// and sends back the cells that flipped.
+// It runs until jobs is closed, so the same goroutine is reused for every turn.
+// The flipped slice is reused on the next job, so it must be consumed before then.
+func worker(s strip, jobs <-chan stripJob, results chan<- []util.Cell) {
+	var flipped []util.Cell
+	for job := range jobs {
+		flipped = job.current.step(job.next, job.rule, s.startY, s.endY, flipped[:0])
+		results <- flipped
+	}
+}
+
+// workerPool is a fixed set of persistent workers, one per strip.
+// Every worker has its own job and result channel so results can be merged in strip order.
+// The pool double-buffers the world: it keeps the board from the previous turn as a spare
+// and writes the next turn into it, so steady-state turns do not allocate.
+type workerPool struct {
+	strips  []strip
+	jobs    []chan stripJob
+	results []chan []util.Cell
+	rule    *ruleTable
+	spare   *board
+	flipped []util.Cell
+}
+
+func newWorkerPool(p Params) *workerPool {
+	strips := splitRows(p.ImageHeight, p.Threads)
+	pool := &workerPool{
+		strips:  strips,
+		rule:    newRuleTable(p.Rule),
+		jobs:    make([]chan stripJob, len(strips)),
+		results: make([]chan []util.Cell, len(strips)),
+	}
+	for i, s := range strips {
+		pool.jobs[i] = make(chan stripJob, 1)
+		pool.results[i] = make(chan []util.Cell, 1)
+		go worker(s, pool.jobs[i], pool.results[i])
+	}
+	return pool
+}
+
+// step has the workers compute the turn after world, each writing its own rows of the next board.
+// The flipped cells are returned in the same row-major order a single worker would produce.
+//
+// world becomes the pool's spare board and the flipped slice is reused,
+// so neither may be used after the following call to step. A local pool never fails.
+func (pool *workerPool) step(world *board) (*board, []util.Cell, error) {
+	next := pool.spare
+	if next == nil || next.width != world.width || next.height != world.height {
+		next = newBoard(world.width, world.height, world.boundary)
+	}
+	for _, jobs := range pool.jobs {
+		jobs <- stripJob{current: world, next: next, rule: pool.rule}
+	}
+
+	pool.flipped = pool.flipped[:0]
+	for _, results := range pool.results {
+		pool.flipped = append(pool.flipped, <-results...)
+	}
+	next.fillGhosts()
+	pool.spare = world
+	return next, pool.flipped, nil
+}
+
+// stop shuts down every worker goroutine.
+func (pool *workerPool) stop() error {
+	for _, jobs := range pool.jobs {
+		close(jobs)
+	}
+	return nil
+}
+
+// detach is the same as stop, as a local run cannot outlive its controller.
+func (pool *workerPool) detach() error {
+	return pool.stop()
+}
+
+// shutdown is the same as stop, as a local run has no other processes to end.
+func (pool *workerPool) shutdown() error {
+	return pool.stop()
+}
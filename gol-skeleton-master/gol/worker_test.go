@@ -0,0 +1,91 @@
+package gol
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestSplitRows(t *testing.T) {
+	for _, test := range []struct{ height, threads int }{{16, 1}, {16, 3}, {512, 16}, {5, 8}, {7, 0}} {
+		strips := splitRows(test.height, test.threads)
+		startY := 0
+		for _, s := range strips {
+			if s.startY != startY || s.endY <= s.startY {
+				t.Fatalf("splitRows(%d, %d) = %v is not a partition of the rows", test.height, test.threads, strips)
+			}
+			startY = s.endY
+		}
+		if startY != test.height {
+			t.Fatalf("splitRows(%d, %d) = %v does not cover every row", test.height, test.threads, strips)
+		}
+	}
+}
+
+func TestSplitWeighted(t *testing.T) {
+	tests := []struct {
+		height  int
+		weights []float64
+		rows    []int
+	}{
+		{16, []float64{1}, []int{16}},
+		{16, []float64{1, 1}, []int{8, 8}},
+		{16, []float64{3, 1}, []int{12, 4}},
+		{512, []float64{1, 2, 1}, []int{128, 256, 128}},
+		{4, []float64{100, 1, 1}, []int{2, 1, 1}},
+		{2, []float64{1, 1, 1, 1}, []int{1, 1}},
+	}
+	for _, test := range tests {
+		strips := splitWeighted(test.height, test.weights)
+		rows := make([]int, len(strips))
+		startY := 0
+		for i, s := range strips {
+			if s.startY != startY || s.endY <= s.startY {
+				t.Fatalf("splitWeighted(%d, %v) = %v is not a partition of the rows", test.height, test.weights, strips)
+			}
+			rows[i] = s.endY - s.startY
+			startY = s.endY
+		}
+		if startY != test.height || fmt.Sprint(rows) != fmt.Sprint(test.rows) {
+			t.Fatalf("splitWeighted(%d, %v) gives strips of %v rows, expected %v", test.height, test.weights, rows, test.rows)
+		}
+	}
+}
+
+// TestWorkerPoolNoAllocs checks that once the spare board and flip buffers exist a turn allocates nothing.
+func TestWorkerPoolNoAllocs(t *testing.T) {
+	p := Params{Threads: 4, ImageWidth: 512, ImageHeight: 512}
+	pool := newWorkerPool(p)
+	defer pool.stop()
+
+	world := newBoard(p.ImageWidth, p.ImageHeight, Torus)
+	world.fromBytes(randomWorld(p.ImageWidth, p.ImageHeight, 1))
+	for i := 0; i < 10; i++ {
+		world, _, _ = pool.step(world)
+	}
+
+	allocs := testing.AllocsPerRun(100, func() {
+		world, _, _ = pool.step(world)
+	})
+	if allocs != 0 {
+		t.Errorf("expected no allocations per turn, got %v", allocs)
+	}
+}
+
+func BenchmarkWorkerPool(b *testing.B) {
+	for _, threads := range []int{1, 4, 16} {
+		p := Params{Threads: threads, ImageWidth: 512, ImageHeight: 512}
+		b.Run(fmt.Sprintf("512x512-%d", threads), func(b *testing.B) {
+			pool := newWorkerPool(p)
+			defer pool.stop()
+			world := newBoard(p.ImageWidth, p.ImageHeight, Torus)
+			world.fromBytes(randomWorld(p.ImageWidth, p.ImageHeight, 1))
+			world, _, _ = pool.step(world)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				world, _, _ = pool.step(world)
+			}
+		})
+	}
+}
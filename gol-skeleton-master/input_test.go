@@ -0,0 +1,61 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// TestInputPath tests that an image loaded from any path is sized from its header,
+// and that the output goes to the directory given.
+func TestInputPath(t *testing.T) {
+	dir := t.TempDir()
+	p := gol.Params{Input: "check/images/16x16x1.pgm", OutputDir: dir, Turns: 99, Threads: 2}
+	events := make(chan gol.Event)
+	go gol.Run(p, events, nil)
+	var cells []util.Cell
+	for event := range events {
+		switch e := event.(type) {
+		case gol.FinalTurnComplete:
+			cells = e.Alive
+		}
+	}
+	p.ImageWidth, p.ImageHeight = 16, 16
+	assertEqualBoard(t, cells, readAliveCells("check/images/16x16x100.pgm", 16, 16), p)
+
+	if _, err := os.Stat(filepath.Join(dir, "16x16x99.pgm")); err != nil {
+		t.Errorf("ERROR: No image written to the output directory: %v", err)
+	}
+}
+
+// TestInputSizeMismatch tests that an image whose header does not match the size asked for
+// is reported as a SizeError in a RunError event, with the events channel closed, rather than a panic.
+func TestInputSizeMismatch(t *testing.T) {
+	p := gol.Params{Input: "images/16x16.pgm", ImageWidth: 64, ImageHeight: 64, Turns: 1, Threads: 1}
+	if _, err := gol.InputParams(p); err == nil {
+		t.Errorf("ERROR: InputParams accepted a 16x16 image for a 64x64 board")
+	}
+
+	events := make(chan gol.Event)
+	errs := make(chan error, 1)
+	go func() { errs <- gol.Run(p, events, nil) }()
+	var got []gol.Event
+	for event := range events {
+		got = append(got, event)
+	}
+	var sizeError *gol.SizeError
+	var runError gol.RunError
+	if len(got) == 1 {
+		runError, _ = got[0].(gol.RunError)
+	}
+	if !errors.As(runError.Err, &sizeError) {
+		t.Errorf("ERROR: Got the events %v, expected a single RunError with a SizeError", got)
+	}
+	if err := <-errs; !errors.As(err, &sizeError) {
+		t.Errorf("ERROR: Run returned %v for a 16x16 image on a 64x64 board, expected a SizeError", err)
+	}
+}
@@ -13,6 +13,8 @@ func TestKeyboard(t *testing.T) {
 	t.Run("p", testKeyboardP)
 	t.Run("s", testKeyboardS)
 	t.Run("q", testKeyboardQ)
+	t.Run("k", testKeyboardK)
+	t.Run("n", testKeyboardN)
 	t.Run("p+s", testKeyboardPS)
 	t.Run("p+q", testKeyboardPQ)
 }
@@ -68,6 +70,52 @@ func testKeyboardP(t *testing.T) {
 	tester.Loop()
 }
 
+func testKeyboardN(t *testing.T) {
+	params := gol.Params{
+		Turns:       20,
+		Threads:     8,
+		ImageWidth:  512,
+		ImageHeight: 512,
+	}
+
+	keyPresses := make(chan rune, 10)
+	events := make(chan gol.Event, 1000)
+
+	golDone := make(chan bool, 1)
+
+	keyPresses <- 'p'
+
+	go func() {
+		gol.Run(params, events, keyPresses)
+		golDone <- true
+	}()
+
+	tester := MakeTester(t, params, keyPresses, events, golDone)
+	tester.SetTestTurn()
+	tester.SetWatchTurns()
+
+	go func() {
+		tester.TestStartsExecuting()
+
+		turn := tester.TestPauses()
+
+		for i := 0; i < 3; i++ {
+			keyPresses <- 'n'
+			turn = tester.TestSteps(turn)
+		}
+
+		tester.TestNoStateChange(2 * time.Second)
+
+		keyPresses <- 'p'
+
+		tester.TestExecutes(turn)
+
+		tester.Stop(false)
+	}()
+
+	tester.Loop()
+}
+
 func testKeyboardS(t *testing.T) {
 	params := gol.Params{
 		Turns:       100000000,
@@ -136,6 +184,41 @@ func testKeyboardQ(t *testing.T) {
 	tester.Loop()
 }
 
+// testKeyboardK tests that 'k' quits a local run just like 'q', as there are no other processes to shut down.
+func testKeyboardK(t *testing.T) {
+	params := gol.Params{
+		Turns:       100000000,
+		Threads:     8,
+		ImageWidth:  512,
+		ImageHeight: 512,
+	}
+
+	keyPresses := make(chan rune, 10)
+	events := make(chan gol.Event, 1000)
+
+	golDone := make(chan bool, 1)
+
+	go func() {
+		gol.Run(params, events, keyPresses)
+		golDone <- true
+	}()
+
+	tester := MakeTester(t, params, keyPresses, events, golDone)
+
+	go func() {
+		tester.TestStartsExecuting()
+
+		time.Sleep(500 * time.Millisecond)
+
+		keyPresses <- 'k'
+		tester.TestOutput()
+		tester.TestQuits()
+		tester.Stop(true)
+	}()
+
+	tester.Loop()
+}
+
 func testKeyboardPS(t *testing.T) {
 	params := gol.Params{
 		Turns:       100000000,
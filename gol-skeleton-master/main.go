@@ -1,12 +1,15 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"runtime"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"uk.ac.bris.cs/gameoflife/gol"
 	"uk.ac.bris.cs/gameoflife/sdl"
@@ -41,6 +44,116 @@ func main() {
 		10000000000,
 		"Specify the number of turns to process. Defaults to 10000000000.")
 
+	flag.Func(
+		"rule",
+		"Specify the life-like rule as a B/S rulestring, e.g. B36/S23. Defaults to B3/S23.",
+		func(rulestring string) (err error) {
+			params.Rule, err = gol.ParseRule(rulestring)
+			return err
+		})
+
+	flag.Func(
+		"boundary",
+		"Specify what lies beyond the edges of the board: torus, dead, reflect, klein or cross. Defaults to torus.",
+		func(name string) (err error) {
+			params.Boundary, err = gol.ParseBoundary(name)
+			return err
+		})
+
+	flag.StringVar(
+		&params.Broker,
+		"broker",
+		"",
+		"Specify the address of a broker to run the simulation on, e.g. 127.0.0.1:8030. Runs locally by default.")
+
+	flag.BoolVar(
+		&params.HaloExchange,
+		"halo",
+		false,
+		"Have the broker's workers keep their strips and exchange edge rows with each other. Needs -broker.")
+
+	flag.StringVar(
+		&params.Input,
+		"in",
+		"",
+		"Specify the file to load instead of images/<h>x<w>.pgm: a PGM image, or a pattern in RLE, plaintext (.cells) "+
+			"or Life 1.06 (.lif) format that is centred on the board. "+
+			"The board size and rule come from the file unless -w, -h or -rule are given.")
+
+	flag.StringVar(
+		&params.OutputDir,
+		"out",
+		"out",
+		"Specify the directory to save PGM images and snapshots in. Defaults to out.")
+
+	flag.StringVar(
+		&params.SnapshotFormat,
+		"snapshot",
+		"rle",
+		"Specify the pattern format of the snapshot saved next to each PGM image: rle, cells or lif. Defaults to rle.")
+
+	flag.BoolVar(
+		&params.PNG,
+		"png",
+		false,
+		"Save a PNG image next to each PGM image.")
+
+	flag.IntVar(
+		&params.Scale,
+		"scale",
+		1,
+		"Specify the size in pixels of each cell in PNG images and GIF recordings. Defaults to 1.")
+
+	var recorder gol.GIFRecorder
+
+	flag.StringVar(
+		&recorder.Path,
+		"gif",
+		"",
+		"Specify a file to record the run to as an animated GIF. Records nothing by default.")
+
+	flag.IntVar(
+		&recorder.Every,
+		"gif-every",
+		1,
+		"Specify how many turns apart the frames of the GIF recording are. Defaults to 1.")
+
+	flag.IntVar(
+		&recorder.Start,
+		"gif-start",
+		0,
+		"Specify the turn the GIF recording starts at. Defaults to 0.")
+
+	flag.IntVar(
+		&recorder.End,
+		"gif-end",
+		0,
+		"Specify the turn the GIF recording ends at, or 0 to record to the last turn. Defaults to 0.")
+
+	flag.DurationVar(
+		&params.CheckpointInterval,
+		"checkpoint",
+		time.Minute,
+		"Specify how often to save a checkpoint to resume the run from with -in, or 0 for only when 's' is pressed. Defaults to 1m.")
+
+	flag.Float64Var(
+		&params.TurnsPerSecond,
+		"tps",
+		0,
+		"Specify the most turns to run a second, or 0 for as many as possible. Change it with '+' and '-' while running. Defaults to 0.")
+
+	flag.BoolVar(
+		&params.FlipRuns,
+		"runs",
+		false,
+		"Send the cells flipped in each turn as runs along their rows rather than as cells. Defaults to false.")
+
+	flag.BoolVar(
+		&params.Rewind,
+		"rewind",
+		false,
+		"Keep the recent turns from the start so ',' can step back to before the run was first paused, rather than keeping them from the first pause. Defaults to false.")
+
 	headless := flag.Bool(
 		"headless",
 		false,
@@ -48,27 +161,79 @@ func main() {
 
 	flag.Parse()
 
+	if params.Input != "" {
+		sized := false
+		flag.Visit(func(f *flag.Flag) {
+			if f.Name == "w" || f.Name == "h" {
+				sized = true
+			}
+		})
+		if !sized {
+			params.ImageWidth, params.ImageHeight = 0, 0
+		}
+	}
+	params, err := gol.InputParams(params)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
 	fmt.Printf("%-10v %v\n", "Threads", params.Threads)
 	fmt.Printf("%-10v %v\n", "Width", params.ImageWidth)
 	fmt.Printf("%-10v %v\n", "Height", params.ImageHeight)
 	fmt.Printf("%-10v %v\n", "Turns", params.Turns)
+	fmt.Printf("%-10v %v\n", "Rule", params.Rule)
+	fmt.Printf("%-10v %v\n", "Boundary", params.Boundary)
 
 	keyPresses := make(chan rune, 10)
 	events := make(chan gol.Event, 1000)
 
-	go sigterm(keyPresses)
+	// SIGTERM and SIGINT quit the run as 'q' does.
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
 
-	go gol.Run(params, events, keyPresses)
+	// The window or the printout, and the GIF recorder, each subscribe to the events of the run.
+	var bus gol.Bus
+	var view *gol.Subscription
 	if !(*headless) {
-		sdl.Run(params, events, keyPresses)
+		view = sdl.Subscribe(&bus)
 	} else {
-		sdl.RunHeadless(events)
+		view = sdl.SubscribeHeadless(&bus)
 	}
-}
+	var recorded chan error
+	if recorder.Path != "" {
+		recording := bus.Subscribe(gol.Filter{}, 1000, gol.Block)
+		recorded = make(chan error, 1)
+		go func() {
+			recorded <- recorder.Record(params, recording.Events(), nil)
+		}()
+	}
+	go bus.Forward(events)
+
+	runErr := make(chan error, 1)
+	go func() {
+		runErr <- gol.RunContext(ctx, params, events, gol.KeyCommands(ctx, keyPresses))
+	}()
 
-func sigterm(keyPresses chan<- rune) {
-	sigterm := make(chan os.Signal, 1)
-	signal.Notify(sigterm, syscall.SIGTERM, syscall.SIGINT)
-	<-sigterm
-	keyPresses <- 'q'
+	if !(*headless) {
+		if err := sdl.Run(params, view, keyPresses); err != nil {
+			// Carry on without the window rather than leave the run with nothing to read its events.
+			fmt.Fprintln(os.Stderr, err)
+			sdl.RunHeadless(view)
+		}
+	} else {
+		sdl.RunHeadless(view)
+	}
+
+	// The run has finished once RunContext returns, and the recording is saved once Record does.
+	err = <-runErr
+	if recorded != nil {
+		if err := <-recorded; err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	}
+	if err != nil && !errors.Is(err, context.Canceled) {
+		stop()
+		os.Exit(1)
+	}
 }
@@ -2,6 +2,7 @@ package main
 
 import (
 	"flag"
+	"fmt"
 	"os"
 	"runtime"
 	"testing"
@@ -29,7 +30,11 @@ func TestMain(m *testing.M) {
 	if !(*sdlFlag) {
 		go test()
 	} else {
-		w = sdl.NewWindow(512, 512)
+		var err error
+		if w, err = sdl.NewWindow(512, 512); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
 		flipCellChan = make(chan util.Cell, 1000)
 		refreshChan = make(chan struct{}, 1)
 		clearPixelsChan = make(chan struct{}, 1)
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// TestPatternFormats tests that the 16x16 image on 0, 1 and 100 turns saves an RLE, .cells or
+// Life 1.06 snapshot that loads back to the check image.
+func TestPatternFormats(t *testing.T) {
+	emptyOutFolder()
+	for _, format := range []string{"rle", "cells", "lif"} {
+		for _, turns := range []int{0, 1, 100} {
+			testName := fmt.Sprintf("%v/16x16x%v", format, turns)
+			t.Run(testName, func(t *testing.T) {
+				p := gol.Params{ImageWidth: 16, ImageHeight: 16, Turns: turns, Threads: 1, SnapshotFormat: format}
+				events := make(chan gol.Event)
+				go gol.Run(p, events, nil)
+				for range events {
+				}
+
+				p = gol.Params{
+					ImageWidth:  16,
+					ImageHeight: 16,
+					Threads:     1,
+					Input:       fmt.Sprintf("out/16x16x%v.%v", turns, format),
+				}
+				events = make(chan gol.Event)
+				go gol.Run(p, events, nil)
+				var cells []util.Cell
+				for event := range events {
+					switch e := event.(type) {
+					case gol.FinalTurnComplete:
+						cells = e.Alive
+					}
+				}
+				expectedAlive := readAliveCells(fmt.Sprintf("check/images/16x16x%v.pgm", turns), 16, 16)
+				assertEqualBoard(t, cells, expectedAlive, p)
+			})
+		}
+	}
+}
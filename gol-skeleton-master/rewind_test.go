@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// TestRewind tests that ',' and '.' step the window back and forward through the turns before a pause,
+// that 's' saves the board in the window, and that unpausing brings the window back to the current turn.
+func TestRewind(t *testing.T) {
+	dir := t.TempDir()
+	p := gol.Params{ImageWidth: 64, ImageHeight: 64, Turns: 100000000, Threads: 4, OutputDir: dir, Rewind: true}
+	keyPresses := make(chan rune, 10)
+	events := make(chan gol.Event, 1000)
+	go gol.Run(p, events, keyPresses)
+	time.AfterFunc(100*time.Millisecond, func() {
+		keyPresses <- 'p'
+	})
+
+	world := make([][]bool, p.ImageHeight)
+	for y := range world {
+		world[y] = make([]bool, p.ImageWidth)
+	}
+	alive := func() []util.Cell {
+		var cells []util.Cell
+		for y, row := range world {
+			for x, cell := range row {
+				if cell {
+					cells = append(cells, util.Cell{X: x, Y: y})
+				}
+			}
+		}
+		return cells
+	}
+
+	paused := -1
+	var turns []int
+	var shown []util.Cell
+	var saved gol.ImageOutputComplete
+	for event := range events {
+		switch e := event.(type) {
+		case gol.CellFlipped:
+			world[e.Cell.Y][e.Cell.X] = !world[e.Cell.Y][e.Cell.X]
+		case gol.CellsFlipped:
+			for _, cell := range e.Cells {
+				world[cell.Y][cell.X] = !world[cell.Y][cell.X]
+			}
+		case gol.StateChange:
+			if e.NewState == gol.Paused {
+				paused = e.CompletedTurns
+				for _, key := range ",,,.sp" {
+					keyPresses <- key
+				}
+			}
+			if e.NewState == gol.Executing && paused >= 0 {
+				keyPresses <- 'q'
+			}
+		case gol.TurnComplete:
+			if paused >= 0 {
+				turns = append(turns, e.CompletedTurns)
+				if len(turns) == 4 {
+					shown = alive()
+				}
+			}
+		case gol.ImageOutputComplete:
+			if paused >= 0 && saved.Filename == "" {
+				saved = e
+			}
+		}
+	}
+
+	if paused < 3 {
+		t.Fatalf("ERROR: Paused at turn %v, too early to step back 3 turns", paused)
+	}
+	expected := []int{paused - 1, paused - 2, paused - 3, paused - 2, paused - 1, paused}
+	if len(turns) < len(expected) || fmt.Sprint(turns[:len(expected)]) != fmt.Sprint(expected) {
+		t.Fatalf("ERROR: Got TurnComplete for turns %v after pausing at turn %v, expected %v to begin with",
+			turns, paused, expected)
+	}
+	if saved.CompletedTurns != paused-2 {
+		t.Errorf("ERROR: 's' saved turn %v, expected the turn in the window, %v", saved.CompletedTurns, paused-2)
+	}
+
+	reference := gol.Params{ImageWidth: 64, ImageHeight: 64, Turns: paused - 2, Threads: 1, OutputDir: t.TempDir()}
+	_, final := finalTurn(t, reference, nil)
+	assertEqualBoard(t, shown, final.Alive, reference)
+	assertEqualBoard(t, readAliveCells(filepath.Join(dir, saved.Filename+".pgm"), 64, 64), final.Alive, reference)
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"os"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// TestRLE tests that the 16x16 image saves an RLE snapshot next to its PGM output,
+// and that loading the snapshot back, sized from its header, gives the check image after 100 turns.
+func TestRLE(t *testing.T) {
+	emptyOutFolder()
+	events := make(chan gol.Event)
+	go gol.Run(gol.Params{ImageWidth: 16, ImageHeight: 16, Threads: 1}, events, nil)
+	for range events {
+	}
+	if _, err := os.Stat("out/16x16x0.rle"); err != nil {
+		t.Fatalf("ERROR: No RLE snapshot written: %v", err)
+	}
+
+	p := gol.Params{Input: "out/16x16x0.rle", Turns: 100, Threads: 4}
+	events = make(chan gol.Event)
+	go gol.Run(p, events, nil)
+	var cells []util.Cell
+	for event := range events {
+		switch e := event.(type) {
+		case gol.FinalTurnComplete:
+			cells = e.Alive
+		}
+	}
+	p.ImageWidth, p.ImageHeight = 16, 16
+	assertEqualBoard(t, cells, readAliveCells("check/images/16x16x100.pgm", 16, 16), p)
+}
@@ -10,9 +10,21 @@ import (
 
 const FPS = 60
 
-func Run(p gol.Params, events <-chan gol.Event, keyPresses chan<- rune) {
-	w := NewWindow(int32(p.ImageWidth), int32(p.ImageHeight))
+// Subscribe subscribes to every event on bus, as the window needs them, merging the flipped cells
+// into one frame when the window falls behind rather than holding up the run.
+func Subscribe(bus *gol.Bus) *gol.Subscription {
+	return bus.Subscribe(gol.Filter{}, 1000, gol.CoalesceFlips)
+}
+
+// Run shows the run in a window until it quits or the subscription ends, and then unsubscribes. If the window
+// cannot be opened or drawn to it returns the error, leaving the subscription to the caller.
+func Run(p gol.Params, subscription *gol.Subscription, keyPresses chan<- rune) error {
+	w, err := NewWindow(int32(p.ImageWidth), int32(p.ImageHeight))
+	if err != nil {
+		return err
+	}
 	defer w.Destroy()
+	events := subscription.Events()
 	dirty := false
 	refreshTicker := time.NewTicker(time.Second / time.Duration(FPS))
 	avgTurns := util.NewAvgTurns()
@@ -38,11 +50,23 @@ sdl:
 						keyPresses <- 'q'
 					case sdl.K_k:
 						keyPresses <- 'k'
+					case sdl.K_n:
+						keyPresses <- 'n'
+					case sdl.K_COMMA:
+						keyPresses <- ','
+					case sdl.K_PERIOD:
+						keyPresses <- '.'
+					case sdl.K_PLUS, sdl.K_EQUALS, sdl.K_KP_PLUS:
+						keyPresses <- '+'
+					case sdl.K_MINUS, sdl.K_KP_MINUS:
+						keyPresses <- '-'
 					}
 				}
 			}
 			if dirty {
-				w.RenderFrame()
+				if err := w.RenderFrame(); err != nil {
+					return err
+				}
 				dirty = false
 			}
 
@@ -57,14 +81,27 @@ sdl:
 				for _, cell := range e.Cells {
 					w.FlipPixel(cell.X, cell.Y) 
 				}
+			case gol.RowsFlipped:
+				for _, run := range e.Runs {
+					for x := run.X; x < run.X+run.Length; x++ {
+						w.FlipPixel(x, run.Y)
+					}
+				}
 			case gol.TurnComplete:
 				dirty = true
 			case gol.AliveCellsCount:
-				fmt.Printf("Completed Turns %-8v %-20v Avg%+5v turns/sec\n", event.GetCompletedTurns(), event, avgTurns.Get(event.GetCompletedTurns()))
+				fmt.Printf("Completed Turns %-8v %-20v %v\n", event.GetCompletedTurns(), event, avgTurns.Report(event.GetCompletedTurns()))
+			case gol.SpeedChanged:
+				avgTurns.SetTarget(e.TurnsPerSecond)
+				fmt.Printf("Completed Turns %-8v %v\n", event.GetCompletedTurns(), event)
 			case gol.FinalTurnComplete:
 				fmt.Printf("Completed Turns %-8v %v\n", event.GetCompletedTurns(), event)
 			case gol.ImageOutputComplete:
 				fmt.Printf("Completed Turns %-8v %v\n", event.GetCompletedTurns(), event)
+			case gol.WorkerLost, gol.WorkerJoined, gol.MembershipChanged:
+				fmt.Printf("Completed Turns %-8v %v\n", event.GetCompletedTurns(), event)
+			case gol.RunError:
+				fmt.Printf("Completed Turns %-8v %v\n", event.GetCompletedTurns(), event)
 			case gol.StateChange:
 				fmt.Printf("Completed Turns %-8v %v\n", event.GetCompletedTurns(), event)
 				if e.NewState == gol.Quitting {
@@ -73,18 +110,37 @@ sdl:
 			}
 		}
 	}
+	subscription.Unsubscribe()
+	return nil
+}
+
+// SubscribeHeadless subscribes to the events on bus that RunHeadless prints, holding up the run rather than miss any.
+func SubscribeHeadless(bus *gol.Bus) *gol.Subscription {
+	return bus.Subscribe(gol.Filter{Types: []gol.Event{
+		gol.AliveCellsCount{}, gol.SpeedChanged{}, gol.FinalTurnComplete{}, gol.ImageOutputComplete{},
+		gol.WorkerLost{}, gol.WorkerJoined{}, gol.MembershipChanged{}, gol.RunError{}, gol.StateChange{},
+	}}, 100, gol.Block)
 }
 
-func RunHeadless(events <-chan gol.Event) {
+// RunHeadless prints the events of the subscription that the window would, until it ends, and then unsubscribes.
+func RunHeadless(subscription *gol.Subscription) {
+	defer subscription.Unsubscribe()
 	avgTurns := util.NewAvgTurns()
-	for event := range events {
+	for event := range subscription.Events() {
 		switch e := event.(type) {
 		case gol.AliveCellsCount:
-			fmt.Printf("Completed Turns %-8v %-20v Avg%+5v turns/sec\n", event.GetCompletedTurns(), event, avgTurns.Get(event.GetCompletedTurns()))
+			fmt.Printf("Completed Turns %-8v %-20v %v\n", event.GetCompletedTurns(), event, avgTurns.Report(event.GetCompletedTurns()))
+		case gol.SpeedChanged:
+			avgTurns.SetTarget(e.TurnsPerSecond)
+			fmt.Printf("Completed Turns %-8v %v\n", event.GetCompletedTurns(), event)
 		case gol.FinalTurnComplete:
 			fmt.Printf("Completed Turns %-8v %v\n", event.GetCompletedTurns(), "Final Turn Complete")
 		case gol.ImageOutputComplete:
 			fmt.Printf("Completed Turns %-8v %v\n", event.GetCompletedTurns(), event)
+		case gol.WorkerLost, gol.WorkerJoined, gol.MembershipChanged:
+			fmt.Printf("Completed Turns %-8v %v\n", event.GetCompletedTurns(), event)
+		case gol.RunError:
+			fmt.Printf("Completed Turns %-8v %v\n", event.GetCompletedTurns(), event)
 		case gol.StateChange:
 			fmt.Printf("Completed Turns %-8v %v\n", event.GetCompletedTurns(), event)
 			if e.NewState == gol.Quitting {
@@ -5,7 +5,6 @@ import (
 	"unsafe"
 	
 	"github.com/veandco/go-sdl2/sdl"
-	"uk.ac.bris.cs/gameoflife/util"
 )
 
 type Window struct {
@@ -20,18 +19,34 @@ func filterEvent(e sdl.Event, userdata interface{}) bool {
 	return e.GetType() == sdl.KEYDOWN || e.GetType() == sdl.QUIT
 }
 
-func NewWindow(width, height int32) *Window {
-	err := sdl.Init(sdl.INIT_EVERYTHING)
-	util.Check(err)
+// NewWindow opens a window showing a width by height board, or returns the error if SDL cannot open one.
+func NewWindow(width, height int32) (*Window, error) {
+	if err := sdl.Init(sdl.INIT_EVERYTHING); err != nil {
+		return nil, err
+	}
 	window, err := sdl.CreateWindow("GOL GUI", sdl.WINDOWPOS_CENTERED, sdl.WINDOWPOS_CENTERED, width, height, sdl.WINDOW_SHOWN)
-	util.Check(err)
+	if err != nil {
+		sdl.Quit()
+		return nil, err
+	}
 	renderer, err := sdl.CreateRenderer(window, -1, sdl.WINDOW_SHOWN)
-	util.Check(err)
+	if err != nil {
+		window.Destroy()
+		sdl.Quit()
+		return nil, err
+	}
 	sdl.SetHint(sdl.HINT_RENDER_SCALE_QUALITY, "linear")
 	err = renderer.SetLogicalSize(width, height)
-	util.Check(err)
-	texture, err := renderer.CreateTexture(sdl.PIXELFORMAT_ARGB8888, sdl.TEXTUREACCESS_STATIC, width, height)
-	util.Check(err)
+	var texture *sdl.Texture
+	if err == nil {
+		texture, err = renderer.CreateTexture(sdl.PIXELFORMAT_ARGB8888, sdl.TEXTUREACCESS_STATIC, width, height)
+	}
+	if err != nil {
+		renderer.Destroy()
+		window.Destroy()
+		sdl.Quit()
+		return nil, err
+	}
 
 	sdl.SetEventFilterFunc(filterEvent, nil)
 	return &Window{
@@ -41,27 +56,34 @@ func NewWindow(width, height int32) *Window {
 		renderer,
 		texture,
 		make([]byte, width*height*4),
-	}
+	}, nil
 }
 
-func (w *Window) Destroy() {
+// Destroy closes the window, returning the first error from freeing it.
+func (w *Window) Destroy() error {
 	err := w.texture.Destroy()
-	util.Check(err)
-	err = w.renderer.Destroy()
-	util.Check(err)
-	err = w.window.Destroy()
-	util.Check(err)
+	if rendererErr := w.renderer.Destroy(); err == nil {
+		err = rendererErr
+	}
+	if windowErr := w.window.Destroy(); err == nil {
+		err = windowErr
+	}
 	sdl.Quit()
+	return err
 }
 
-func (w *Window) RenderFrame() {
-	err := w.texture.Update(nil, unsafe.Pointer(&w.pixels[0]), int(w.Width*4))
-	util.Check(err)
-	err = w.renderer.Clear()
-	util.Check(err)
-	err = w.renderer.Copy(w.texture, nil, nil)
-	util.Check(err)
+func (w *Window) RenderFrame() error {
+	if err := w.texture.Update(nil, unsafe.Pointer(&w.pixels[0]), int(w.Width*4)); err != nil {
+		return err
+	}
+	if err := w.renderer.Clear(); err != nil {
+		return err
+	}
+	if err := w.renderer.Copy(w.texture, nil, nil); err != nil {
+		return err
+	}
 	w.renderer.Present()
+	return nil
 }
 
 func (w *Window) PollEvent() sdl.Event {
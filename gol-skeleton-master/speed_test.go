@@ -0,0 +1,77 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+)
+
+// TestSpeed tests that -tps caps the turns per second, that '+' and '-' change the cap,
+// and that a capped run still pauses and quits straight away.
+func TestSpeed(t *testing.T) {
+	t.Run("cap", func(t *testing.T) {
+		p := gol.Params{ImageWidth: 16, ImageHeight: 16, Turns: 25, Threads: 2, OutputDir: t.TempDir(), TurnsPerSecond: 50}
+		events := make(chan gol.Event, 1000)
+		start := time.Now()
+		go gol.Run(p, events, make(chan rune, 10))
+		var speeds []float64
+		for event := range events {
+			if e, ok := event.(gol.SpeedChanged); ok {
+				speeds = append(speeds, e.TurnsPerSecond)
+			}
+		}
+		if elapsed := time.Since(start); elapsed < 480*time.Millisecond {
+			t.Errorf("ERROR: 25 turns at 50 turns/sec took %v, expected at least 480ms", elapsed)
+		}
+		if len(speeds) != 1 || speeds[0] != 50 {
+			t.Errorf("ERROR: Got the speeds %v, expected [50] at the start", speeds)
+		}
+	})
+
+	t.Run("keys", func(t *testing.T) {
+		p := gol.Params{ImageWidth: 16, ImageHeight: 16, Turns: 100000000, Threads: 2, OutputDir: t.TempDir(), TurnsPerSecond: 1}
+		keyPresses := make(chan rune, 10)
+		events := make(chan gol.Event, 1000)
+		go gol.Run(p, events, keyPresses)
+		var speeds []float64
+		var paused, quit time.Duration
+		var pressed time.Time
+		for event := range events {
+			switch e := event.(type) {
+			case gol.SpeedChanged:
+				speeds = append(speeds, e.TurnsPerSecond)
+				switch len(speeds) {
+				case 1, 2:
+					keyPresses <- '+'
+				case 3:
+					keyPresses <- '-'
+				case 4:
+					pressed = time.Now()
+					keyPresses <- 'p'
+				}
+			case gol.StateChange:
+				switch e.NewState {
+				case gol.Paused:
+					paused = time.Since(pressed)
+					pressed = time.Now()
+					keyPresses <- 'q'
+				case gol.Quitting:
+					quit = time.Since(pressed)
+				}
+			}
+		}
+		expected := []float64{1, 2, 5, 2}
+		if len(speeds) != len(expected) {
+			t.Fatalf("ERROR: Got the speeds %v, expected %v", speeds, expected)
+		}
+		for i := range expected {
+			if speeds[i] != expected[i] {
+				t.Fatalf("ERROR: Got the speeds %v, expected %v", speeds, expected)
+			}
+		}
+		if paused > 300*time.Millisecond || quit > 300*time.Millisecond {
+			t.Errorf("ERROR: Pausing took %v and quitting took %v at 2 turns/sec, expected them straight away", paused, quit)
+		}
+	})
+}
@@ -1,6 +1,7 @@
 package util
 
 import (
+	"fmt"
 	"math"
 	"sync"
 	"time"
@@ -14,6 +15,7 @@ type AvgTurns struct {
 	lastCalled        time.Time
 	bufTurns          [BUF_SIZE]int
 	bufDurations      [BUF_SIZE]time.Duration
+	target            float64
 	mutex             sync.Mutex
 }
 
@@ -47,3 +49,23 @@ func (avg *AvgTurns) Get(completedTurns int) (avgTurns int) {
 	avgTurns = int(sumTurns) / int(math.Round(math.Max(sumDurations.Seconds(), 1)))
 	return avgTurns
 }
+
+// SetTarget sets the turns per second the run is capped at, for Report to compare against.
+// A target of 0 means the run is not capped.
+func (avg *AvgTurns) SetTarget(tps float64) {
+	avg.mutex.Lock()
+	avg.target = tps
+	avg.mutex.Unlock()
+}
+
+// Report returns the average turns per second as Get does, against the target if there is one.
+func (avg *AvgTurns) Report(completedTurns int) string {
+	turns := avg.Get(completedTurns)
+	avg.mutex.Lock()
+	target := avg.target
+	avg.mutex.Unlock()
+	if target == 0 {
+		return fmt.Sprintf("Avg%+5v turns/sec", turns)
+	}
+	return fmt.Sprintf("Avg%+5v turns/sec of %v", turns, target)
+}
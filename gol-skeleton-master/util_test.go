@@ -121,6 +121,7 @@ type Tester struct {
 	world        [][]byte
 	aliveMap     map[int]int
 	testTurn     bool
+	watchTurns   bool
 	sdlSync      chan bool
 }
 
@@ -160,6 +161,12 @@ func (tester *Tester) SetTestTurn() {
 	tester.testTurn = true
 }
 
+// SetWatchTurns passes TurnComplete and AliveCellsCount events on to the tests as well.
+// Only use it for runs that are paused almost at once, or the tests fall behind.
+func (tester *Tester) SetWatchTurns() {
+	tester.watchTurns = true
+}
+
 func (tester *Tester) SetTestSdl() {
 	tester.testTurn = true
 	tester.sdlSync = make(chan bool)
@@ -212,6 +219,15 @@ func (tester *Tester) Loop() {
 					tester.world[cell.Y][cell.X] = ^tester.world[cell.Y][cell.X]
 					flipCell(cell)
 				}
+			case gol.RowsFlipped:
+				if tester.testTurn {
+					limitedAssert.Assert(e.CompletedTurns == tester.turn || e.CompletedTurns == tester.turn+1,
+						"Expected completed %v or %v turns for RowsFlipped event, got %v instead", tester.turn, tester.turn+1, e.CompletedTurns)
+				}
+				for _, cell := range e.Cells() {
+					tester.world[cell.Y][cell.X] = ^tester.world[cell.Y][cell.X]
+					flipCell(cell)
+				}
 			case gol.TurnComplete:
 				if tester.testTurn {
 					limitedAssert.Reset()
@@ -219,6 +235,9 @@ func (tester *Tester) Loop() {
 						"Expected completed %v or %v turns for TurnComplete event, got %v instead", tester.turn, tester.turn+1, e.CompletedTurns)
 				}
 				tester.turn++
+				if tester.watchTurns {
+					tester.HandleEvent(e)
+				}
 				refresh()
 				if tester.sdlSync != nil {
 					tester.sdlSync <- true
@@ -226,6 +245,9 @@ func (tester *Tester) Loop() {
 				}
 			case gol.AliveCellsCount:
 				fmt.Printf("Completed Turns %-8v %-20v Avg%+5v turns/sec\n", event.GetCompletedTurns(), event, avgTurns.Get(event.GetCompletedTurns()))
+				if tester.watchTurns {
+					tester.HandleEvent(e)
+				}
 			case gol.ImageOutputComplete:
 				fmt.Printf("Completed Turns %-8v %v\n", event.GetCompletedTurns(), event)
 				tester.HandleEvent(e)
@@ -393,6 +415,37 @@ func (tester *Tester) TestPauses() int {
 	}
 }
 
+// TestSteps checks that the paused run takes exactly one step on from turn, with a TurnComplete
+// and an AliveCellsCount for the new turn and no change of state, and returns the new turn.
+func (tester *Tester) TestSteps(turn int) int {
+	tester.t.Logf("Testing for a single step from turn %v", turn)
+	timeout(tester.t, 2*time.Second, func() {
+		stepped := false
+		for e := range tester.eventWatcher {
+			switch e := e.(type) {
+			case gol.StateChange:
+				tester.t.Errorf("ERROR: Recieved unexpected StateChange event %v while stepping", e)
+			case gol.TurnComplete:
+				assert(tester.t, e.CompletedTurns == turn+1,
+					"TurnComplete after a step from turn %v should have a CompletedTurns of %v, not %v", turn, turn+1, e.CompletedTurns)
+				stepped = true
+			case gol.AliveCellsCount:
+				if !stepped {
+					continue
+				}
+				assert(tester.t, e.CompletedTurns == turn+1,
+					"AliveCellsCount after a step from turn %v should have a CompletedTurns of %v, not %v", turn, turn+1, e.CompletedTurns)
+				expected := tester.aliveMap[turn+1]
+				assert(tester.t, e.CellsCount == expected,
+					"At turn %v expected %v alive cells, got %v instead", turn+1, expected, e.CellsCount)
+				return
+			}
+		}
+	}, "No TurnComplete and AliveCellsCount events received in 2 seconds after 'n'")
+	tester.TestAlive()
+	return turn + 1
+}
+
 func (tester *Tester) TestFinishes(allowedTime int) {
 	tester.t.Logf("Testing for FinalTurnComplete event")
 	timeout(tester.t, time.Duration(allowedTime)*time.Second, func() {
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// main starts a worker node with 'go run ./worker' and registers it with a broker.
+func main() {
+	ip := flag.String(
+		"ip",
+		"127.0.0.1",
+		"Specify the IP address the broker should use to reach this worker. Defaults to 127.0.0.1.")
+
+	port := flag.String(
+		"port",
+		"8031",
+		"Specify the port to listen on. Defaults to 8031.")
+
+	broker := flag.String(
+		"broker",
+		"127.0.0.1:8030",
+		"Specify the address of the broker. Defaults to 127.0.0.1:8030.")
+
+	flag.Parse()
+
+	listener, err := net.Listen("tcp", ":"+*port)
+	util.Check(err)
+	address := net.JoinHostPort(*ip, *port)
+	fmt.Println("Worker listening on", address)
+	util.Check(gol.RegisterWorker(*broker, address))
+
+	// On Ctrl-C hand the worker's rows back to the broker before exiting.
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+	go func() {
+		<-interrupts
+		util.Check(gol.DeregisterWorker(*broker, address))
+		fmt.Println("Worker left", *broker)
+		os.Exit(0)
+	}()
+
+	util.Check(gol.ServeWorker(listener))
+}